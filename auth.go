@@ -1,9 +1,12 @@
 package butler
 
 type Ath struct {
-	success  bool
-	result   string
-	response *Response
+	success   bool
+	result    string
+	response  *Response
+	principal any
+	scopes    []string
+	roles     []string
 }
 
 var Auth Ath
@@ -15,35 +18,101 @@ type AuthHandler func(request *Request) *Ath
 // Client is not authenticated with the server (server does not know who the client is)
 func (Ath) Unauthorized() *Ath {
 	return &Ath{
-		success: false,
-		result:  "unauthorized",
-		response: &Response{
-			Status: 401,
-		},
+		success:  false,
+		result:   "unauthorized",
+		response: Respond.Problem(401),
 	}
 }
 
 // Client does not have the rights to access (server knows the client identity but refuses access)
 func (Ath) Forbidden() *Ath {
 	return &Ath{
-		success: false,
-		result:  "forbidden",
-		response: &Response{
-			Status: 403,
-		},
+		success:  false,
+		result:   "forbidden",
+		response: Respond.Problem(403),
 	}
 }
 
 func (Ath) Ok() *Ath {
 	return &Ath{
 		success: true,
+		result:  "ok",
 	}
 }
 
+// Same as Auth.Ok().WithPrincipal(principal)
+func (Ath) OkWith(principal any) *Ath {
+	return Auth.Ok().WithPrincipal(principal)
+}
+
+// Attaches the authenticated principal (e.g. the user or the api client) to the auth result,
+// it can be then retrieved in the middlewares and handlers with request.Principal() or butler.GetPrincipal()
+func (a *Ath) WithPrincipal(principal any) *Ath {
+	a.principal = principal
+	return a
+}
+
+// Attaches the scopes (permissions) granted to the authenticated client,
+// those can be then checked with request.HasScope()
+func (a *Ath) WithScopes(scopes ...string) *Ath {
+	a.scopes = append(a.scopes, scopes...)
+	return a
+}
+
+// Attaches the roles of the authenticated client, those can be then checked with request.HasRole()
+// or the Role() requirement
+func (a *Ath) WithRoles(roles ...string) *Ath {
+	a.roles = append(a.roles, roles...)
+	return a
+}
+
+// Adds a WWW-Authenticate header to the failed auth response, e.g. `Bearer realm="api"`
+func (a *Ath) WithChallenge(challenge string) *Ath {
+	if a.response != nil {
+		a.response.Headers.Add("WWW-Authenticate", challenge)
+	}
+	return a
+}
+
+// Replaces the response that is sent when the auth fails. If the given response does not have
+// a status, the status of the auth result (401 or 403) is used.
+func (a *Ath) WithResponse(response *Response) *Ath {
+	if a.response == nil {
+		return a
+	}
+
+	if response.Status == 0 {
+		response.Status = a.response.Status
+	}
+	for _, h := range a.response.Headers.httpHeaders {
+		if !response.Headers.Has(h.name) {
+			for _, v := range h.values {
+				response.Headers.Add(h.name, v)
+			}
+		}
+	}
+
+	a.response = response
+	return a
+}
+
+// Serializes the given argument using JSON and sends it as the body of the failed auth response
+func (a *Ath) WithJSON(data any) *Ath {
+	if a.response != nil {
+		a.response.JSON(data)
+	}
+	return a
+}
+
 func (a *Ath) IsSuccessful() bool {
 	return a.success
 }
 
+// One of: `ok`, `unauthorized`, `forbidden`
+func (a *Ath) Result() string {
+	return a.result
+}
+
 func (a *Ath) SendResponse(ctx *Request) error {
 	return a.response.send(ctx)
 }
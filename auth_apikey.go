@@ -0,0 +1,69 @@
+package butler
+
+import (
+	"fmt"
+)
+
+type APIKeyOptions struct {
+	// Store used to look up the api keys, keys are looked up by their APIKeyID()
+	Store CredentialStore
+	// Name of the header the key is read from
+	//
+	// Default: `X-API-Key`
+	Header string
+	// Name of the query param the key is read from, when the header is not present.
+	// Leave empty to not accept keys from the query.
+	QueryParam string
+	// Name of the cookie the key is read from, when neither the header nor the query param is present.
+	// Leave empty to not accept keys from the cookies.
+	CookieName string
+	// Realm included in the WWW-Authenticate header of the failed auth responses
+	//
+	// Default: `api`
+	Realm string
+}
+
+// APIKeyAuth creates an AuthHandler that verifies the api key of the request against the CredentialStore.
+func APIKeyAuth(opts APIKeyOptions) AuthHandler {
+	if opts.Store == nil {
+		panic("APIKeyAuth requires a credential store")
+	}
+	if opts.Header == "" {
+		opts.Header = "X-API-Key"
+	}
+	if opts.Realm == "" {
+		opts.Realm = "api"
+	}
+
+	challenge := fmt.Sprintf(`APIKey realm=%q, header=%q`, opts.Realm, opts.Header)
+
+	return func(request *Request) *Ath {
+		key := request.Headers.Get(opts.Header)
+		if key == "" && opts.QueryParam != "" {
+			key = request.EchoContext().QueryParam(opts.QueryParam)
+		}
+		if key == "" && opts.CookieName != "" {
+			cookie, err := request.GetCookie(opts.CookieName)
+			if err == nil {
+				key = cookie.Value
+			}
+		}
+
+		if key == "" {
+			return Auth.Unauthorized().WithChallenge(challenge)
+		}
+
+		credential, err := opts.Store.Lookup(request.Context(), APIKeyID(key))
+		if err != nil {
+			request.Logger.Error("credential store lookup failed: ", err)
+			return Auth.Unauthorized().WithChallenge(challenge)
+		}
+
+		if credential == nil || !VerifySecret(key, credential.Hash) {
+			request.Logger.Debug("api key auth failed, invalid key")
+			return Auth.Unauthorized().WithChallenge(challenge + `, error="invalid_key"`)
+		}
+
+		return credentialAuthResult(credential)
+	}
+}
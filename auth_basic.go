@@ -0,0 +1,71 @@
+package butler
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type BasicAuthOptions struct {
+	// Store used to look up the user credentials
+	Store CredentialStore
+	// Realm included in the WWW-Authenticate header of the failed auth responses
+	//
+	// Default: `restricted`
+	Realm string
+}
+
+// used to keep the response time the same for existing and non existing users
+var dummyBcryptHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// BasicAuth creates an AuthHandler that verifies the HTTP Basic credentials of the request against the
+// password hashes from the CredentialStore.
+func BasicAuth(opts BasicAuthOptions) AuthHandler {
+	if opts.Store == nil {
+		panic("BasicAuth requires a credential store")
+	}
+	if opts.Realm == "" {
+		opts.Realm = "restricted"
+	}
+
+	challenge := fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, opts.Realm)
+
+	return func(request *Request) *Ath {
+		username, password, ok := request.HttpRequest().BasicAuth()
+		if !ok {
+			return Auth.Unauthorized().WithChallenge(challenge)
+		}
+
+		credential, err := opts.Store.Lookup(request.Context(), username)
+		if err != nil {
+			request.Logger.Error("credential store lookup failed: ", err)
+			return Auth.Unauthorized().WithChallenge(challenge)
+		}
+
+		if credential == nil {
+			bcrypt.CompareHashAndPassword(dummyBcryptHash(), []byte(password))
+			request.Logger.Debug("basic auth failed, unknown user: ", username)
+			return Auth.Unauthorized().WithChallenge(challenge)
+		}
+
+		if !VerifySecret(password, credential.Hash) {
+			request.Logger.Debug("basic auth failed, invalid password for user: ", username)
+			return Auth.Unauthorized().WithChallenge(challenge)
+		}
+
+		return credentialAuthResult(credential)
+	}
+}
+
+func credentialAuthResult(credential *Credential) *Ath {
+	principal := credential.Principal
+	if principal == nil {
+		principal = credential.ID
+	}
+
+	return Auth.OkWith(principal).WithRoles(credential.Roles...).WithScopes(credential.Scopes...)
+}
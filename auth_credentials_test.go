@@ -0,0 +1,121 @@
+package butler_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+)
+
+func TestBasicAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	argonHash := fmt.Sprintf(
+		"$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s",
+		8*1024, 1, 1,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("argon-pass"), salt, 1, 8*1024, 1, 32)),
+	)
+	bcryptHash, err := f.HashPassword("bcrypt-pass")
+	noErr(err)
+
+	htpasswd := path.Join(t.TempDir(), ".htpasswd")
+	noErr(os.WriteFile(htpasswd, []byte("# users\njohn:"+bcryptHash+"\njane:"+argonHash+"\n"), 0600))
+
+	store, err := f.NewHtpasswdStore(htpasswd)
+	noErr(err)
+
+	server := f.CreateServer()
+	monitor := &recordingMonitor{}
+	server.Monitor(monitor)
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/me",
+		Auth:   f.BasicAuth(f.BasicAuthOptions{Store: store, Realm: "admin"}),
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text(request.Principal().(string))
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	basic := func(user, pass string) header {
+		return header{"Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))}
+	}
+
+	body, resp := request("GET", baseUrl+"/me", nil, basic("john", "bcrypt-pass"))
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("john", string(body))
+
+	body, resp = request("GET", baseUrl+"/me", nil, basic("jane", "argon-pass"))
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("jane", string(body))
+
+	for _, h := range []header{basic("john", "argon-pass"), basic("nobody", "bcrypt-pass"), {"X-None", "1"}} {
+		_, resp = request("GET", baseUrl+"/me", nil, h)
+		assert.Equal(401, resp.StatusCode)
+		assert.Equal(`Basic realm="admin", charset="UTF-8"`, resp.Header.Get("WWW-Authenticate"))
+	}
+
+	assert.Equal(f.MonitorStep.Auth, monitor.Records()[2].Steps[0].Step)
+	assert.Equal("unauthorized", monitor.Records()[2].Steps[0].Result)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	store := f.NewMemoryCredentialStore()
+	store.AddAPIKey("key-123", f.Credential{Principal: "billing-service", Scopes: []string{"invoices"}})
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/invoices",
+		Auth: f.APIKeyAuth(f.APIKeyOptions{
+			Store:      store,
+			QueryParam: "api_key",
+			CookieName: "api_key",
+		}),
+		Require: f.Scope("invoices"),
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text(request.Principal().(string))
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/invoices", nil, header{"X-API-Key", "key-123"})
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("billing-service", string(body))
+
+	_, resp = request("GET", baseUrl+"/invoices?api_key=key-123", nil)
+	assert.Equal(200, resp.StatusCode)
+
+	_, resp = request("GET", baseUrl+"/invoices", nil, header{"Cookie", "api_key=key-123"})
+	assert.Equal(200, resp.StatusCode)
+
+	_, resp = request("GET", baseUrl+"/invoices", nil, header{"X-API-Key", "key-456"})
+	assert.Equal(401, resp.StatusCode)
+	assert.Equal(`APIKey realm="api", header="X-API-Key", error="invalid_key"`, resp.Header.Get("WWW-Authenticate"))
+
+	_, resp = request("GET", baseUrl+"/invoices", nil)
+	assert.Equal(401, resp.StatusCode)
+	assert.Equal(`APIKey realm="api", header="X-API-Key"`, resp.Header.Get("WWW-Authenticate"))
+
+	cred, err := store.Lookup(context.Background(), f.APIKeyID("key-123"))
+	noErr(err)
+	assert.True(f.VerifySecret("key-123", cred.Hash))
+	assert.False(f.VerifySecret("key-1234", cred.Hash))
+}
@@ -0,0 +1,607 @@
+package butler
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type JWTOptions struct {
+	// Secret used to verify HS256 tokens, regardless of their `kid` header. The secret is only used by this
+	// handler, it is not added to the Keys.
+	Secret []byte
+	// Keys used to verify the token signatures. When the token header contains a `kid`, only the key
+	// with the matching ID will be used.
+	//
+	// Keys can be added and removed from the set at any time, which allows for key rotation.
+	Keys *JWTKeySet
+	// Path to a local JWKS file (RFC 7517) containing the keys used to verify the tokens. The file is
+	// reloaded when it changes on the disk.
+	JWKSFile string
+	// How often the JWKS file should be checked for changes.
+	//
+	// Default: 1 minute
+	JWKSReloadInterval time.Duration
+	// Algorithms that will be accepted, tokens signed with other algorithms are rejected.
+	//
+	// Default: `HS256`, `RS256`, `ES256`
+	Algorithms []string
+	// When set, the `iss` claim of the token must match this value
+	Issuer string
+	// When set, the `aud` claim of the token must contain this value
+	Audience string
+	// Allowed difference between the server clock and the clock of the token issuer, when checking
+	// the `exp` and `nbf` claims.
+	//
+	// Default: 30 seconds
+	ClockSkew time.Duration
+	// Name of the cookie the token should be read from, when the request does not have
+	// a `Authorization: Bearer` header. Leave empty to only read the token from the header.
+	CookieName string
+	// Realm included in the WWW-Authenticate header of the failed auth responses
+	Realm string
+	// Optional function that converts the verified claims to the principal, when it returns an error
+	// the request will be rejected as unauthorized.
+	//
+	// By default the JWTClaims are used as the principal.
+	Principal func(claims JWTClaims) (any, error)
+}
+
+// JWTAuth creates an AuthHandler that verifies the JWT bearer token of the request.
+//
+// Verified claims can be accessed with request.JWTClaims(). Scopes from the `scope` or `scp` claims
+// and roles from the `roles` claim are granted to the request.
+func JWTAuth(opts JWTOptions) AuthHandler {
+	v, err := newJWTVerifier(opts)
+	if err != nil {
+		panic("invalid JWT auth options: " + err.Error())
+	}
+
+	return func(request *Request) *Ath {
+		token := v.extractToken(request)
+		if token == "" {
+			return v.reject("", "")
+		}
+
+		claims, err := v.verify(token, time.Now())
+		if err != nil {
+			request.Logger.Debug("JWT verification failed: ", err)
+			// the reason is not disclosed to the client, apart from the token being expired
+			if errors.Is(err, errJWTExpired) {
+				return v.reject("invalid_token", "token expired")
+			}
+			return v.reject("invalid_token", "invalid token")
+		}
+
+		var principal any = claims
+		if opts.Principal != nil {
+			principal, err = opts.Principal(claims)
+			if err != nil {
+				request.Logger.Debug("JWT principal rejected: ", err)
+				return v.reject("invalid_token", "invalid token")
+			}
+		}
+
+		jwtClaimsKey.Set(request, claims)
+
+		return Auth.OkWith(principal).WithScopes(claims.Scopes()...).WithRoles(claims.Roles()...)
+	}
+}
+
+var jwtClaimsKey = NewKey[JWTClaims]("jwt_claims")
+
+// Returns the claims of the token verified by the JWTAuth handler, or nil if there was none
+func (r *Request) JWTClaims() JWTClaims {
+	claims, _ := jwtClaimsKey.Get(r)
+	return claims
+}
+
+// #region Claims
+
+type JWTClaims map[string]any
+
+func (c JWTClaims) String(name string) string {
+	v, _ := c[name].(string)
+	return v
+}
+
+func (c JWTClaims) Subject() string {
+	return c.String("sub")
+}
+
+// Identifies the principal by the issuer and the subject of the token, or returns an empty string if the token
+// has no subject
+func (c JWTClaims) PrincipalID() string {
+	if c.Subject() == "" {
+		return ""
+	}
+	return c.Issuer() + ":" + c.Subject()
+}
+
+func (c JWTClaims) Issuer() string {
+	return c.String("iss")
+}
+
+// Returns the values of the `aud` claim, which can be either a single string or an array of strings
+func (c JWTClaims) Audience() []string {
+	if aud, ok := c["aud"].(string); ok {
+		return []string{aud}
+	}
+	return c.stringList("aud", "")
+}
+
+// Returns the scopes from either the `scope` (space separated string) or the `scp` claim
+func (c JWTClaims) Scopes() []string {
+	if _, ok := c["scope"]; ok {
+		return c.stringList("scope", " ")
+	}
+	return c.stringList("scp", " ")
+}
+
+func (c JWTClaims) Roles() []string {
+	return c.stringList("roles", " ")
+}
+
+func (c JWTClaims) ExpiresAt() (time.Time, bool) {
+	return c.time("exp")
+}
+
+func (c JWTClaims) NotBefore() (time.Time, bool) {
+	return c.time("nbf")
+}
+
+func (c JWTClaims) IssuedAt() (time.Time, bool) {
+	return c.time("iat")
+}
+
+func (c JWTClaims) time(name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		if err == nil {
+			return time.Unix(n, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (c JWTClaims) stringList(name string, separator string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool { return strings.ContainsRune(separator, r) })
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, el := range v {
+			if s, ok := el.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// #endregion Claims
+
+// #region Keys
+
+type JWTKey struct {
+	// Key ID, matched against the `kid` header of the tokens
+	ID string
+	// One of: `HS256`, `RS256`, `ES256`
+	Algorithm string
+	// []byte for HS256, *rsa.PublicKey for RS256, *ecdsa.PublicKey for ES256
+	Key any
+}
+
+// Thread safe set of keys used to verify JWT signatures
+type JWTKeySet struct {
+	mx   sync.RWMutex
+	keys []JWTKey
+}
+
+func NewJWTKeySet(keys ...JWTKey) *JWTKeySet {
+	return &JWTKeySet{keys: keys}
+}
+
+// Adds the key to the set, if a key with the same ID already exists it is replaced
+func (ks *JWTKeySet) Add(key JWTKey) {
+	ks.mx.Lock()
+	defer ks.mx.Unlock()
+
+	for idx := range ks.keys {
+		if key.ID != "" && ks.keys[idx].ID == key.ID {
+			ks.keys[idx] = key
+			return
+		}
+	}
+	ks.keys = append(ks.keys, key)
+}
+
+func (ks *JWTKeySet) Remove(keyID string) {
+	ks.mx.Lock()
+	defer ks.mx.Unlock()
+
+	ks.keys = slices.DeleteFunc(ks.keys, func(k JWTKey) bool {
+		return k.ID == keyID
+	})
+}
+
+// Replaces all the keys in the set
+func (ks *JWTKeySet) Replace(keys ...JWTKey) {
+	ks.mx.Lock()
+	defer ks.mx.Unlock()
+	ks.keys = keys
+}
+
+func (ks *JWTKeySet) find(keyID string, alg string) []JWTKey {
+	ks.mx.RLock()
+	defer ks.mx.RUnlock()
+
+	found := make([]JWTKey, 0, 1)
+	for _, k := range ks.keys {
+		if k.Algorithm != alg {
+			continue
+		}
+		if keyID != "" && k.ID != keyID {
+			continue
+		}
+		found = append(found, k)
+	}
+	return found
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// symmetric
+	K string `json:"k"`
+}
+
+// Parses a JSON Web Key Set (RFC 7517). Supports RSA, EC (P-256) and symmetric keys, keys meant for encryption
+// and keys restricted by their `alg` member to an algorithm other than RS256, ES256 or HS256 are skipped.
+func ParseJWKS(data []byte) ([]JWTKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]JWTKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.toJWTKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%s': %w", k.Kid, err)
+		}
+		if k.Alg != "" && k.Alg != key.Algorithm {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (k jwk) toJWTKey() (JWTKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return JWTKey{k.Kid, "RS256", pub}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return JWTKey{}, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		return JWTKey{k.Kid, "ES256", pub}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		return JWTKey{k.Kid, "HS256", secret}, nil
+	}
+
+	return JWTKey{}, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// #endregion Keys
+
+// #region Verification
+
+var errJWTMalformed = errors.New("token is malformed")
+var errJWTExpired = errors.New("token has expired")
+
+type jwtVerifier struct {
+	opts JWTOptions
+	keys *JWTKeySet
+	// owned by the verifier, so that the secret does not leak into a key set shared with other handlers
+	secret     *JWTKeySet
+	jwks       *jwksFile
+	algorithms []string
+	skew       time.Duration
+}
+
+func newJWTVerifier(opts JWTOptions) (*jwtVerifier, error) {
+	v := &jwtVerifier{
+		opts:       opts,
+		keys:       opts.Keys,
+		algorithms: opts.Algorithms,
+		skew:       opts.ClockSkew,
+	}
+
+	if v.keys == nil {
+		v.keys = NewJWTKeySet()
+	}
+	if len(opts.Secret) > 0 {
+		v.secret = NewJWTKeySet(JWTKey{Algorithm: "HS256", Key: opts.Secret})
+	}
+	if len(v.algorithms) == 0 {
+		v.algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+	if v.skew == 0 {
+		v.skew = 30 * time.Second
+	}
+
+	if opts.JWKSFile != "" {
+		interval := opts.JWKSReloadInterval
+		if interval == 0 {
+			interval = time.Minute
+		}
+		v.jwks = &jwksFile{path: opts.JWKSFile, interval: interval, keys: NewJWTKeySet()}
+		err := v.jwks.load()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+func (v *jwtVerifier) extractToken(request *Request) string {
+	authHeader := request.Headers.Get("Authorization")
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "bearer ") {
+		return strings.TrimSpace(authHeader[7:])
+	}
+
+	if v.opts.CookieName != "" {
+		cookie, err := request.GetCookie(v.opts.CookieName)
+		if err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+func (v *jwtVerifier) reject(errCode string, description string) *Ath {
+	challenge := "Bearer"
+	params := []string{}
+	if v.opts.Realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", v.opts.Realm))
+	}
+	if errCode != "" {
+		params = append(params, fmt.Sprintf("error=%q", errCode))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	return Auth.Unauthorized().WithChallenge(challenge)
+}
+
+func (v *jwtVerifier) verify(token string, now time.Time) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errJWTMalformed
+	}
+
+	headerJson, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errJWTMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err = json.Unmarshal(headerJson, &header)
+	if err != nil {
+		return nil, errJWTMalformed
+	}
+
+	if !slices.Contains(v.algorithms, header.Alg) {
+		return nil, fmt.Errorf("algorithm '%s' is not allowed", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errJWTMalformed
+	}
+
+	keys := v.keys.find(header.Kid, header.Alg)
+	if v.secret != nil {
+		keys = append(keys, v.secret.find("", header.Alg)...)
+	}
+	if v.jwks != nil {
+		keys = append(keys, v.jwks.getKeys().find(header.Kid, header.Alg)...)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no key found to verify the token")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verifyJWTSignature(header.Alg, key.Key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("signature is invalid")
+	}
+
+	claimsJson, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errJWTMalformed
+	}
+	var claims JWTClaims
+	err = json.Unmarshal(claimsJson, &claims)
+	if err != nil {
+		return nil, errJWTMalformed
+	}
+
+	for _, name := range []string{"exp", "nbf"} {
+		_, present := claims[name]
+		if _, ok := claims.time(name); present && !ok {
+			return nil, fmt.Errorf("the '%s' claim is not a numeric date", name)
+		}
+	}
+	if exp, ok := claims.ExpiresAt(); ok && now.After(exp.Add(v.skew)) {
+		return nil, errJWTExpired
+	}
+	if nbf, ok := claims.NotBefore(); ok && now.Add(v.skew).Before(nbf) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if v.opts.Issuer != "" && claims.Issuer() != v.opts.Issuer {
+		return nil, errors.New("token issuer is invalid")
+	}
+	if v.opts.Audience != "" && !slices.Contains(claims.Audience(), v.opts.Audience) {
+		return nil, errors.New("token audience is invalid")
+	}
+
+	return claims, nil
+}
+
+func verifyJWTSignature(alg string, key any, signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+
+	return false
+}
+
+type jwksFile struct {
+	path      string
+	interval  time.Duration
+	keys      *JWTKeySet
+	mx        sync.Mutex
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func (f *jwksFile) load() error {
+	stat, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	f.keys.Replace(keys...)
+	f.modTime = stat.ModTime()
+	f.lastCheck = time.Now()
+	return nil
+}
+
+func (f *jwksFile) getKeys() *JWTKeySet {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	if time.Since(f.lastCheck) >= f.interval {
+		f.lastCheck = time.Now()
+		stat, err := os.Stat(f.path)
+		if err == nil && !stat.ModTime().Equal(f.modTime) {
+			// keep using the previous keys if the new file cannot be loaded
+			f.load()
+		}
+	}
+
+	return f.keys
+}
+
+// #endregion Verification
@@ -0,0 +1,227 @@
+package butler_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(alg string, kid string, key any, claims map[string]any) string {
+	b64 := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "RS256":
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		noErr(err)
+		signature = sig
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		noErr(err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return signed + "." + b64.EncodeToString(signature)
+}
+
+func TestJWTAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	secret := []byte("top-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	noErr(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noErr(err)
+	otherEcKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noErr(err)
+
+	b64 := base64.RawURLEncoding
+	jwksFile := path.Join(t.TempDir(), "jwks.json")
+	rsaN, rsaE := b64.EncodeToString(rsaKey.N.Bytes()), b64.EncodeToString([]byte{1, 0, 1})
+	jwks := fmt.Sprintf(
+		`{"keys":[
+			{"kty":"RSA","kid":"rsa-1","use":"sig","n":"%s","e":"%s"},
+			{"kty":"RSA","kid":"rsa-384","alg":"RS384","use":"sig","n":"%s","e":"%s"}
+		]}`,
+		rsaN, rsaE, rsaN, rsaE,
+	)
+	noErr(os.WriteFile(jwksFile, []byte(jwks), 0600))
+
+	keys := f.NewJWTKeySet(f.JWTKey{ID: "ec-1", Algorithm: "ES256", Key: &ecKey.PublicKey})
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/me",
+		Auth: f.JWTAuth(f.JWTOptions{
+			Secret:     secret,
+			Keys:       keys,
+			JWKSFile:   jwksFile,
+			Issuer:     "butler",
+			Audience:   "api",
+			CookieName: "token",
+			Realm:      "api",
+		}),
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			if !request.HasScope("orders:read") {
+				return f.Respond.Forbidden()
+			}
+			return f.Respond.Ok().Text(request.JWTClaims().Subject())
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	now := time.Now().Unix()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "user-1",
+			"iss":   "butler",
+			"aud":   []string{"api", "other"},
+			"exp":   now + 60,
+			"scope": "orders:read orders:write",
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+	get := func(token string) (string, int, string) {
+		body, resp := request("GET", baseUrl+"/me", nil, header{"Authorization", "Bearer " + token})
+		return string(body), resp.StatusCode, resp.Header.Get("WWW-Authenticate")
+	}
+
+	for _, token := range []string{
+		signJWT("HS256", "", secret, claims(nil)),
+		signJWT("RS256", "rsa-1", rsaKey, claims(nil)),
+		signJWT("ES256", "ec-1", ecKey, claims(nil)),
+		// expired, but within the clock skew
+		signJWT("HS256", "", secret, claims(map[string]any{"exp": now - 10})),
+	} {
+		body, status, _ := get(token)
+		assert.Equal(200, status)
+		assert.Equal("user-1", body)
+	}
+
+	_, status, challenge := get(signJWT("HS256", "", secret, claims(map[string]any{"exp": now - 120})))
+	assert.Equal(401, status)
+	assert.Equal(`Bearer realm="api", error="invalid_token", error_description="token expired"`, challenge)
+
+	_, status, challenge = get(signJWT("HS256", "", secret, claims(map[string]any{"nbf": now + 120})))
+	assert.Equal(401, status)
+	assert.Equal(`Bearer realm="api", error="invalid_token", error_description="invalid token"`, challenge)
+	_, status, _ = get(signJWT("HS256", "", secret, claims(map[string]any{"exp": "never"})))
+	assert.Equal(401, status)
+	_, status, _ = get(signJWT("HS256", "", secret, claims(map[string]any{"nbf": "2020-01-01"})))
+	assert.Equal(401, status)
+	_, status, _ = get(signJWT("HS256", "", secret, claims(map[string]any{"iss": "someone"})))
+	assert.Equal(401, status)
+	_, status, _ = get(signJWT("HS256", "", secret, claims(map[string]any{"aud": "other"})))
+	assert.Equal(401, status)
+	_, status, _ = get(signJWT("HS256", "", []byte("wrong"), claims(nil)))
+	assert.Equal(401, status)
+	_, status, _ = get(signJWT("ES256", "ec-1", otherEcKey, claims(nil)))
+	assert.Equal(401, status)
+	_, status, _ = get(signJWT("none", "", secret, claims(nil)))
+	assert.Equal(401, status)
+	// the key is restricted to a different algorithm by the JWKS
+	_, status, _ = get(signJWT("RS256", "rsa-384", rsaKey, claims(nil)))
+	assert.Equal(401, status)
+
+	// tampered payload
+	token := strings.Split(signJWT("HS256", "", secret, claims(nil)), ".")
+	token[1] = b64.EncodeToString([]byte(`{"sub":"admin","iss":"butler","aud":"api"}`))
+	_, status, _ = get(strings.Join(token, "."))
+	assert.Equal(401, status)
+
+	body, resp := request("GET", baseUrl+"/me", nil)
+	assert.Equal(401, resp.StatusCode)
+	assert.Equal(`Bearer realm="api"`, resp.Header.Get("WWW-Authenticate"))
+	assert.Equal("application/problem+json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(`{"type":"about:blank","title":"Unauthorized","status":401}`, string(body))
+
+	// token from cookie
+	body, resp = request("GET", baseUrl+"/me", nil,
+		header{"Cookie", "token=" + signJWT("HS256", "", secret, claims(nil))})
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("user-1", string(body))
+
+	// scopes from the token are granted to the request
+	_, status, _ = get(signJWT("HS256", "", secret, claims(map[string]any{"scope": "orders:write"})))
+	assert.Equal(403, status)
+
+	// key rotation
+	keys.Add(f.JWTKey{ID: "ec-2", Algorithm: "ES256", Key: &otherEcKey.PublicKey})
+	keys.Remove("ec-1")
+	_, status, _ = get(signJWT("ES256", "ec-2", otherEcKey, claims(nil)))
+	assert.Equal(200, status)
+	_, status, _ = get(signJWT("ES256", "ec-1", ecKey, claims(nil)))
+	assert.Equal(401, status)
+}
+
+func TestJWTSecretsWithSharedKeySet(t *testing.T) {
+	assert := assert.New(t)
+
+	shared := f.NewJWTKeySet()
+	secretA := []byte("secret-a")
+	secretB := []byte("secret-b")
+
+	server := f.CreateServer()
+	for name, secret := range map[string][]byte{"a": secretA, "b": secretB} {
+		server.Add(&f.BasicEndpoint[f.NoParams]{
+			Method: "GET",
+			Path:   "/" + name,
+			Auth:   f.JWTAuth(f.JWTOptions{Secret: secret, Keys: shared, Audience: "api"}),
+			Handler: func(request *f.Request, params f.NoParams) *f.Response {
+				return f.Respond.Ok()
+			},
+		})
+	}
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	get := func(path string, token string) int {
+		_, resp := request("GET", baseUrl+path, nil, header{"Authorization", "Bearer " + token})
+		return resp.StatusCode
+	}
+	claims := map[string]any{"sub": "user-1", "aud": "api"}
+
+	assert.Equal(200, get("/a", signJWT("HS256", "", secretA, claims)))
+	assert.Equal(200, get("/b", signJWT("HS256", "", secretB, claims)))
+	// the secrets are not shared through the key set
+	assert.Equal(401, get("/b", signJWT("HS256", "", secretA, claims)))
+	assert.Equal(401, get("/a", signJWT("HS256", "", secretB, claims)))
+
+	// the secret is used regardless of the key ID
+	assert.Equal(200, get("/a", signJWT("HS256", "key-1", secretA, claims)))
+
+	// a single string audience is not split
+	assert.Equal(401, get("/a", signJWT("HS256", "", secretA, map[string]any{"sub": "user-1", "aud": "api,other"})))
+}
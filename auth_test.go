@@ -0,0 +1,87 @@
+package butler_test
+
+import (
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthResults(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	monitor := &recordingMonitor{}
+	server.Monitor(monitor)
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/unauthorized",
+		Auth: func(request *f.Request) *f.Ath {
+			return f.Auth.Unauthorized().
+				WithChallenge(`Bearer realm="api"`).
+				WithJSON(map[string]string{"error": "missing token"})
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/forbidden",
+		Auth: func(request *f.Request) *f.Ath {
+			return f.Auth.Forbidden().WithResponse(f.Respond.NotFound().Text("not here"))
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/ok",
+		Auth: func(request *f.Request) *f.Ath {
+			return f.Auth.OkWith(TestUser{2, "Jane"}).WithScopes("orders:read")
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			user := request.Principal().(TestUser)
+			if !request.HasScope("orders:read") || request.HasScope("orders:write") {
+				return f.Respond.Forbidden()
+			}
+			return f.Respond.Ok().Text(user.Name)
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/unauthorized", nil)
+	assert.Equal(401, resp.StatusCode)
+	assert.Equal(`Bearer realm="api"`, resp.Header.Get("WWW-Authenticate"))
+	assert.Equal(`{"error":"missing token"}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/forbidden", nil)
+	assert.Equal(404, resp.StatusCode)
+	assert.Equal("not here", string(body))
+
+	body, resp = request("GET", baseUrl+"/ok", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("Jane", string(body))
+
+	waitUntil(func() bool {
+		return len(monitor.Records()) == 3
+	})
+
+	results := map[string]string{}
+	for _, record := range monitor.Records() {
+		assert.Equal("auth", record.Steps[0].Step)
+		assert.NotNil(record.Steps[0].End)
+		results[record.UrlPath] = record.Steps[0].Result
+	}
+
+	assert.Equal("unauthorized", results["/unauthorized"])
+	assert.Equal("forbidden", results["/forbidden"])
+	assert.Equal("ok", results["/ok"])
+}
@@ -0,0 +1,166 @@
+package butler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Requirement is a declarative authorization rule, checked after all the auth handlers have succeeded.
+//
+// Requirements of the parent groups and of the endpoint must all be satisfied for the request to be handled.
+type Requirement interface {
+	// Returns true if the request is allowed to access the endpoint
+	IsSatisfied(request *Request) bool
+	// Human readable description of the requirement, shown in the api documentation
+	Describe() string
+}
+
+// Principals can implement this interface to provide their roles, alternatively
+// the roles can be added to the auth result with Auth.Ok().WithRoles()
+type RoleHolder interface {
+	HasRole(role string) bool
+}
+
+type roleRequirement struct {
+	role string
+}
+
+// Requires the request principal to have the given role
+func Role(role string) Requirement {
+	return roleRequirement{role}
+}
+
+func (r roleRequirement) IsSatisfied(request *Request) bool {
+	return request.HasRole(r.role)
+}
+
+func (r roleRequirement) Describe() string {
+	return fmt.Sprintf("role %q", r.role)
+}
+
+type scopeRequirement struct {
+	scope string
+}
+
+// Requires the given scope to be granted to the request by the auth handlers
+func Scope(scope string) Requirement {
+	return scopeRequirement{scope}
+}
+
+func (r scopeRequirement) IsSatisfied(request *Request) bool {
+	return request.HasScope(r.scope)
+}
+
+func (r scopeRequirement) Describe() string {
+	return fmt.Sprintf("scope %q", r.scope)
+}
+
+type authenticatedRequirement struct{}
+
+// Requires the auth handlers to provide a principal
+func Authenticated() Requirement {
+	return authenticatedRequirement{}
+}
+
+func (authenticatedRequirement) IsSatisfied(request *Request) bool {
+	return request.Principal() != nil
+}
+
+func (authenticatedRequirement) Describe() string {
+	return "authenticated"
+}
+
+type funcRequirement struct {
+	description string
+	check       func(request *Request) bool
+}
+
+// Creates a custom requirement, e.g. checking if the principal is the owner of the resource
+func RequireFunc(description string, check func(request *Request) bool) Requirement {
+	return funcRequirement{description, check}
+}
+
+func (r funcRequirement) IsSatisfied(request *Request) bool {
+	return r.check(request)
+}
+
+func (r funcRequirement) Describe() string {
+	return r.description
+}
+
+type anyOfRequirement struct {
+	requirements []Requirement
+}
+
+// Satisfied when at least one of the given requirements is satisfied
+func AnyOf(requirements ...Requirement) Requirement {
+	return anyOfRequirement{requirements}
+}
+
+func (r anyOfRequirement) IsSatisfied(request *Request) bool {
+	return slices.ContainsFunc(r.requirements, func(req Requirement) bool {
+		return req.IsSatisfied(request)
+	})
+}
+
+func (r anyOfRequirement) Describe() string {
+	return describeRequirements(r.requirements, " or ")
+}
+
+type allOfRequirement struct {
+	requirements []Requirement
+}
+
+// Satisfied when all of the given requirements are satisfied
+func AllOf(requirements ...Requirement) Requirement {
+	return allOfRequirement{requirements}
+}
+
+func (r allOfRequirement) IsSatisfied(request *Request) bool {
+	for _, req := range r.requirements {
+		if !req.IsSatisfied(request) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r allOfRequirement) Describe() string {
+	return describeRequirements(r.requirements, " and ")
+}
+
+func describeRequirements(requirements []Requirement, separator string) string {
+	descriptions := make([]string, 0, len(requirements))
+	for _, req := range requirements {
+		d := req.Describe()
+		switch req.(type) {
+		case anyOfRequirement, allOfRequirement:
+			if len(requirements) > 1 {
+				d = "(" + d + ")"
+			}
+		}
+		descriptions = append(descriptions, d)
+	}
+	return strings.Join(descriptions, separator)
+}
+
+func appendRequirement(requirements []Requirement, requirement Requirement) []Requirement {
+	if requirement == nil {
+		return requirements
+	}
+	return append(slices.Clip(requirements), requirement)
+}
+
+// checks the requirements and returns the auth result that should be sent if any of them fails
+func checkRequirements(request *Request, requirements []Requirement) *Ath {
+	for _, req := range requirements {
+		if !req.IsSatisfied(request) {
+			if request.principal == nil && len(request.scopes) == 0 && len(request.roles) == 0 {
+				return Auth.Unauthorized()
+			}
+			return Auth.Forbidden()
+		}
+	}
+	return nil
+}
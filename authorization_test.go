@@ -0,0 +1,124 @@
+package butler_test
+
+import (
+	"strings"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirements(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	monitor := &recordingMonitor{}
+	server.Monitor(monitor)
+
+	api := &f.Group{
+		Path: "/api",
+		// roles and scopes are taken from the headers, for testing purposes only
+		Auth: func(request *f.Request) *f.Ath {
+			user := request.Headers.Get("X-User")
+			if user == "" {
+				return f.Auth.Ok()
+			}
+			return f.Auth.OkWith(user).
+				WithRoles(strings.Fields(request.Headers.Get("X-Roles"))...).
+				WithScopes(strings.Fields(request.Headers.Get("X-Scopes"))...)
+		},
+		Require: f.Authenticated(),
+	}
+
+	api.Add(&f.BasicEndpoint[f.NoParams]{
+		Method:  "POST",
+		Path:    "/orders",
+		Require: f.AnyOf(f.Role("admin"), f.Scope("orders:write")),
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text("created")
+		},
+	})
+
+	api.Add(&f.RestEndpoints[BooksQueryParams, BookResource]{
+		Path:     "/books",
+		Resource: BookResource{},
+		Require:  f.Scope("books"),
+		OperationRequirements: f.RestRequirements{
+			Delete: f.Role("admin"),
+		},
+	})
+
+	server.Add(api)
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	status := func(method string, path string, headers ...header) int {
+		_, resp := request(method, baseUrl+path, nil, headers...)
+		return resp.StatusCode
+	}
+
+	assert.Equal(401, status("POST", "/api/orders"))
+	assert.Equal(403, status("POST", "/api/orders", header{"X-User", "john"}))
+	assert.Equal(200, status("POST", "/api/orders", header{"X-User", "john"}, header{"X-Roles", "admin"}))
+	assert.Equal(200, status("POST", "/api/orders", header{"X-User", "john"}, header{"X-Scopes", "orders:write"}))
+
+	assert.Equal(403, status("GET", "/api/books", header{"X-User", "john"}))
+	assert.Equal(200, status("GET", "/api/books", header{"X-User", "john"}, header{"X-Scopes", "books"}))
+	assert.Equal(403, status("DELETE", "/api/books/missing", header{"X-User", "john"}, header{"X-Scopes", "books"}))
+	assert.NotEqual(403, status("DELETE", "/api/books/missing",
+		header{"X-User", "john"}, header{"X-Scopes", "books"}, header{"X-Roles", "admin"}))
+
+	record := monitor.Records()[1]
+	assert.Equal(f.MonitorStep.Authorization, record.Steps[1].Step)
+	assert.Equal("forbidden", record.Steps[1].Result)
+}
+
+type roleUser struct {
+	roles []string
+}
+
+func (u roleUser) HasRole(role string) bool {
+	for _, r := range u.roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequirementsWithRoleHolder(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/admin",
+		Auth: func(request *f.Request) *f.Ath {
+			return f.Auth.OkWith(roleUser{strings.Fields(request.Headers.Get("X-Roles"))})
+		},
+		Require: f.AllOf(
+			f.Role("staff"),
+			f.RequireFunc("owner of the account", func(request *f.Request) bool {
+				return request.Headers.Get("X-Owner") == "yes"
+			}),
+		),
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	_, resp := request("GET", baseUrl+"/admin", nil, header{"X-Roles", "staff"})
+	assert.Equal(403, resp.StatusCode)
+	_, resp = request("GET", baseUrl+"/admin", nil, header{"X-Roles", "staff"}, header{"X-Owner", "yes"})
+	assert.Equal(200, resp.StatusCode)
+
+	assert.Equal(
+		`role "admin" or (scope "a" and scope "b")`,
+		f.AnyOf(f.Role("admin"), f.AllOf(f.Scope("a"), f.Scope("b"))).Describe(),
+	)
+}
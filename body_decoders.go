@@ -0,0 +1,162 @@
+package butler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	echo "github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// BodyDecoder decodes the body of the request into the target, which is a pointer to the body type of the endpoint
+type BodyDecoder func(request *Request, target any) error
+
+const (
+	MIMEApplicationJSON        = "application/json"
+	MIMEApplicationXML         = "application/xml"
+	MIMETextXML                = "text/xml"
+	MIMEApplicationForm        = "application/x-www-form-urlencoded"
+	MIMEMultipartForm          = "multipart/form-data"
+	MIMEApplicationMessagePack = "application/msgpack"
+	MIMEApplicationCBOR        = "application/cbor"
+)
+
+// Decodes JSON bodies, fields that are not present in the body type are ignored
+func JSONDecoder(request *Request, target any) error {
+	return json.NewDecoder(request.HttpRequest().Body).Decode(target)
+}
+
+// Decodes JSON bodies, rejecting the fields that are not present in the body type and any data following the
+// JSON value
+func StrictJSONDecoder(request *Request, target any) error {
+	decoder := json.NewDecoder(request.HttpRequest().Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+func XMLDecoder(request *Request, target any) error {
+	return xml.NewDecoder(request.HttpRequest().Body).Decode(target)
+}
+
+// Decodes `application/x-www-form-urlencoded` bodies, fields are matched by the `form` struct tag
+func FormDecoder(request *Request, target any) error {
+	return (&echo.DefaultBinder{}).BindBody(request.EchoContext(), target)
+}
+
+// Decodes MessagePack bodies, fields are matched by the `json` struct tag
+func MessagePackDecoder(request *Request, target any) error {
+	decoder := msgpack.NewDecoder(request.HttpRequest().Body)
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(target)
+}
+
+// Decodes CBOR bodies, fields are matched by the `cbor` or the `json` struct tag
+func CBORDecoder(request *Request, target any) error {
+	return cbor.NewDecoder(request.HttpRequest().Body).Decode(target)
+}
+
+// Returns the decoders used when the server does not specify its own
+func DefaultBodyDecoders() map[string]BodyDecoder {
+	return map[string]BodyDecoder{
+		MIMEApplicationJSON:        JSONDecoder,
+		MIMEApplicationXML:         XMLDecoder,
+		MIMETextXML:                XMLDecoder,
+		MIMEApplicationForm:        FormDecoder,
+		MIMEMultipartForm:          MultipartDecoder,
+		MIMEApplicationMessagePack: MessagePackDecoder,
+		MIMEApplicationCBOR:        CBORDecoder,
+	}
+}
+
+// bodyDecoders holds the decoders an endpoint accepts, resolved once when the endpoint is registered
+type bodyDecoders struct {
+	decoders     map[string]BodyDecoder
+	contentTypes []string
+}
+
+// Merges the decoders of the server and the endpoint and limits them to the accepted content types,
+// panics if one of the accepted types has no decoder. Runs when the endpoint is registered, changes made to the
+// decoders of the server afterwards do not affect the endpoint. Content types are matched case-insensitively.
+func resolveBodyDecoders(server *Server, endpointDecoders map[string]BodyDecoder, accept []string) *bodyDecoders {
+	serverDecoders := server.BodyDecoders
+	if serverDecoders == nil {
+		serverDecoders = DefaultBodyDecoders()
+	}
+
+	decoders := make(map[string]BodyDecoder, len(serverDecoders)+len(endpointDecoders))
+	for contentType, decoder := range serverDecoders {
+		decoders[strings.ToLower(contentType)] = decoder
+	}
+	for contentType, decoder := range endpointDecoders {
+		decoders[strings.ToLower(contentType)] = decoder
+	}
+
+	if len(accept) > 0 {
+		accepted := make(map[string]BodyDecoder, len(accept))
+		for _, contentType := range accept {
+			contentType = strings.ToLower(contentType)
+			decoder, ok := decoders[contentType]
+			if !ok || decoder == nil {
+				panic(fmt.Sprintf("no body decoder for the accepted content type %q", contentType))
+			}
+			accepted[contentType] = decoder
+		}
+		decoders = accepted
+	}
+
+	maps.DeleteFunc(decoders, func(_ string, decoder BodyDecoder) bool { return decoder == nil })
+
+	return &bodyDecoders{
+		decoders:     decoders,
+		contentTypes: slices.Sorted(maps.Keys(decoders)),
+	}
+}
+
+// Decodes the request body into the target, returns a response if the body could not be decoded. Requests
+// without a body are left with the zero value of the target. Media types with the `+json` suffix that have no
+// decoder of their own are decoded by the JSON decoder.
+func (d *bodyDecoders) decode(request *Request, target any) *Response {
+	httpRequest := request.HttpRequest()
+	if httpRequest.Body == nil || httpRequest.Body == http.NoBody || httpRequest.ContentLength == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(httpRequest.Header.Get(echo.HeaderContentType))
+	decoder, ok := d.decoders[mediaType]
+	if !ok && strings.HasSuffix(mediaType, "+json") {
+		decoder, ok = d.decoders[MIMEApplicationJSON]
+	}
+	if err != nil || !ok {
+		return Respond.Problem(415, fmt.Sprintf(
+			"unsupported content type, expected one of: %s", strings.Join(d.contentTypes, ", "),
+		))
+	}
+
+	if err := decoder(request, target); err != nil {
+		request.Logger.Error(err)
+		var verr ValidationErrors
+		if errors.As(err, &verr) {
+			return verr.Response()
+		}
+		if IsBodyTooLarge(err) {
+			return Respond.Problem(413, "the request body is too large")
+		}
+		return Respond.Problem(400, "the request body could not be parsed")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package butler_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type decodedBook struct {
+	Title string `json:"title" xml:"title" form:"title"`
+	Pages int    `json:"pages" xml:"pages" form:"pages"`
+}
+
+func postBody(url string, contentType string, body []byte) (string, int) {
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	noErr(err)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	noErr(err)
+	return string(respBody), resp.StatusCode
+}
+
+func TestBodyDecoders(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := func(request *f.Request, params f.NoParams, body *decodedBook) *f.Response {
+		return f.Respond.Ok().JSON(body)
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.Endpoint[f.NoParams, decodedBook]{
+		Method:  "POST",
+		Path:    "/books",
+		Handler: handler,
+	})
+	server.Add(&f.Endpoint[f.NoParams, decodedBook]{
+		Method:             "POST",
+		Path:               "/strict",
+		BodyDecoders:       map[string]f.BodyDecoder{f.MIMEApplicationJSON: f.StrictJSONDecoder},
+		AcceptContentTypes: []string{f.MIMEApplicationJSON},
+		Handler:            handler,
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	expected := `{"title":"Dune","pages":412}`
+
+	body, status := postBody(baseUrl+"/books", "application/json; charset=utf-8", []byte(`{"title":"Dune","pages":412,"isbn":"x"}`))
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+
+	body, status = postBody(baseUrl+"/books", "application/xml", []byte(`<book><title>Dune</title><pages>412</pages></book>`))
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+
+	body, status = postBody(baseUrl+"/books", "application/x-www-form-urlencoded", []byte(`title=Dune&pages=412`))
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+
+	payload, err := msgpack.Marshal(map[string]any{"title": "Dune", "pages": 412})
+	noErr(err)
+	body, status = postBody(baseUrl+"/books", "application/msgpack", payload)
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+
+	payload, err = cbor.Marshal(map[string]any{"title": "Dune", "pages": 412})
+	noErr(err)
+	body, status = postBody(baseUrl+"/books", "application/cbor", payload)
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+
+	body, status = postBody(baseUrl+"/books", "text/plain", []byte(`Dune`))
+	assert.Equal(415, status)
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Unsupported Media Type",
+		"status": 415,
+		"detail": "unsupported content type, expected one of: application/cbor, application/json, application/msgpack, application/x-www-form-urlencoded, application/xml, multipart/form-data, text/xml"
+	}`, body)
+
+	body, status = postBody(baseUrl+"/strict", "application/json", []byte(`{"title":"Dune","pages":412}`))
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+
+	_, status = postBody(baseUrl+"/strict", "application/json", []byte(`{"title":"Dune","isbn":"x"}`))
+	assert.Equal(400, status)
+
+	_, status = postBody(baseUrl+"/strict", "application/json", []byte(`{"title":"Dune"} {}`))
+	assert.Equal(400, status)
+
+	_, status = postBody(baseUrl+"/strict", "application/xml", []byte(`<book><title>Dune</title></book>`))
+	assert.Equal(415, status)
+
+	// media types with the +json suffix fall back to the JSON decoder
+	body, status = postBody(baseUrl+"/books", "application/vnd.book+json", []byte(`{"title":"Dune","pages":412}`))
+	assert.Equal(200, status)
+	assert.JSONEq(expected, body)
+}
+
+func TestBodyDecoderContentTypeCase(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.BodyDecoders = map[string]f.BodyDecoder{"Application/JSON": f.JSONDecoder}
+
+	endpoint := &f.Endpoint[f.NoParams, decodedBook]{
+		Method:       "POST",
+		Path:         "/books",
+		BodyDecoders: map[string]f.BodyDecoder{"Application/Vnd.Book+XML": f.XMLDecoder},
+		Handler: func(request *f.Request, params f.NoParams, body *decodedBook) *f.Response {
+			return f.Respond.Ok().JSON(body)
+		},
+	}
+	server.Add(endpoint)
+
+	assert.Equal([]string{"application/json", "application/vnd.book+xml"}, endpoint.GetAcceptContentTypes())
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, status := postBody(baseUrl+"/books", "application/json", []byte(`{"title":"Dune"}`))
+	assert.Equal(200, status)
+	assert.JSONEq(`{"title":"Dune","pages":0}`, body)
+
+	body, status = postBody(baseUrl+"/books", "application/vnd.book+xml", []byte(`<book><title>Dune</title></book>`))
+	assert.Equal(200, status)
+	assert.JSONEq(`{"title":"Dune","pages":0}`, body)
+}
+
+func TestAcceptContentTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.BodyDecoders = map[string]f.BodyDecoder{f.MIMEApplicationJSON: f.JSONDecoder}
+
+	endpoint := &f.Endpoint[f.NoParams, decodedBook]{
+		Method:       "POST",
+		Path:         "/books",
+		BodyDecoders: map[string]f.BodyDecoder{"application/vnd.book+json": f.StrictJSONDecoder},
+		Handler: func(request *f.Request, params f.NoParams, body *decodedBook) *f.Response {
+			return f.Respond.Ok()
+		},
+	}
+	server.Add(endpoint)
+
+	assert.Equal([]string{"application/json", "application/vnd.book+json"}, endpoint.GetAcceptContentTypes())
+
+	assert.PanicsWithValue(`no body decoder for the accepted content type "application/xml"`, func() {
+		server.Add(&f.Endpoint[f.NoParams, decodedBook]{
+			Method:             "POST",
+			Path:               "/xml",
+			AcceptContentTypes: []string{f.MIMEApplicationXML},
+			Handler: func(request *f.Request, params f.NoParams, body *decodedBook) *f.Response {
+				return f.Respond.Ok()
+			},
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package butler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// limitedBody wraps the request body, reading past the limit fails with a *http.MaxBytesError
+// and marks the request, so that the client receives a 413 response regardless of how the
+// handler dealt with the error.
+type limitedBody struct {
+	io.ReadCloser
+	request *Request
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			b.request.bodyTooLarge = true
+		}
+	}
+	return n, err
+}
+
+// Applies the body size limit to the request. Returns the response that should be sent instead if the
+// declared Content-Length already exceeds the limit.
+func limitRequestBody(request *Request, maxBodySize int64) *Response {
+	if maxBodySize <= 0 {
+		return nil
+	}
+
+	httpRequest := request.HttpRequest()
+	if httpRequest.ContentLength > maxBodySize {
+		return Respond.Problem(413, "the request body is too large")
+	}
+
+	if httpRequest.Body != nil && httpRequest.Body != http.NoBody {
+		httpRequest.Body = &limitedBody{
+			ReadCloser: http.MaxBytesReader(request.EchoContext().Response(), httpRequest.Body, maxBodySize),
+			request:    request,
+		}
+	}
+
+	return nil
+}
+
+// Returns true if the error was caused by the request body exceeding the MaxBodySize limit
+func IsBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
@@ -0,0 +1,88 @@
+package butler_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+type uploadBody struct {
+	Name string `json:"name"`
+}
+
+func TestMaxBodySize(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.MaxBodySize = 64
+
+	server.Add(&f.Endpoint[f.NoParams, uploadBody]{
+		Method: "POST",
+		Path:   "/json",
+		Handler: func(request *f.Request, params f.NoParams, body *uploadBody) *f.Response {
+			return f.Respond.Ok().Text(body.Name)
+		},
+	})
+
+	uploads := &f.Group{Path: "/uploads", MaxBodySize: 1024}
+	uploads.Add(&f.StreamEndpoint[f.NoParams]{
+		Method: "POST",
+		Path:   "/raw",
+		Handler: func(request *f.Request, params f.NoParams, body io.Reader) *f.Response {
+			n, err := io.Copy(io.Discard, body)
+			if err != nil {
+				assert.True(f.IsBodyTooLarge(err))
+				return f.Respond.BadRequest()
+			}
+			return f.Respond.Ok().Text(strings.Repeat("x", int(n)))
+		},
+	})
+	uploads.Add(&f.StreamEndpoint[f.NoParams]{
+		Method:      "POST",
+		Path:        "/unlimited",
+		MaxBodySize: -1,
+		Handler: func(request *f.Request, params f.NoParams, body io.Reader) *f.Response {
+			n, err := io.Copy(io.Discard, body)
+			noErr(err)
+			return f.Respond.Ok().Text(strings.Repeat("x", int(n)))
+		},
+	})
+	server.Add(uploads)
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("POST", baseUrl+"/json", uploadBody{Name: "report.pdf"})
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("report.pdf", string(body))
+
+	// rejected based on the Content-Length, before the body is read
+	_, resp = request("POST", baseUrl+"/json", uploadBody{Name: strings.Repeat("a", 100)})
+	assert.Equal(413, resp.StatusCode)
+
+	// body without a Content-Length is limited while reading
+	sendChunked := func(path string, body []byte) *http.Response {
+		req, err := http.NewRequest("POST", baseUrl+path, io.MultiReader(bytes.NewReader(body)))
+		noErr(err)
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1
+		req.Close = true
+		resp, err := http.DefaultClient.Do(req)
+		noErr(err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(413, sendChunked("/json", []byte(`{"name":"`+strings.Repeat("a", 100)+`"}`)).StatusCode)
+	assert.Equal(200, sendChunked("/uploads/raw", bytes.Repeat([]byte("a"), 1024)).StatusCode)
+	assert.Equal(413, sendChunked("/uploads/raw", bytes.Repeat([]byte("a"), 1025)).StatusCode)
+	assert.Equal(200, sendChunked("/uploads/unlimited", bytes.Repeat([]byte("a"), 4096)).StatusCode)
+
+	_, resp = request("POST", baseUrl+"/uploads/raw", bytes.Repeat([]byte("a"), 2048))
+	assert.Equal(413, resp.StatusCode)
+}
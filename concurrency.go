@@ -0,0 +1,260 @@
+package butler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyLimit limits how many requests can be handled at once. When set on a group, the limit is shared by
+// all the endpoints within the group. The same ConcurrencyLimit can also be assigned to multiple endpoints,
+// to share the limit between them.
+type ConcurrencyLimit struct {
+	// Maximum number of requests handled at the same time
+	Limit int
+	// Maximum number of requests waiting for a free slot. Requests that do not fit in the queue receive
+	// a 503 response immediately.
+	//
+	// Default: 0 (no queue)
+	QueueSize int
+	// Maximum time a request can wait in the queue, before receiving a 503 response
+	//
+	// Default: 5 seconds
+	QueueTimeout time.Duration
+	// Value of the Retry-After header of the 503 responses
+	//
+	// Default: 1 second
+	RetryAfter time.Duration
+
+	initOnce sync.Once
+	slots    chan struct{}
+	waiting  atomic.Int64
+}
+
+// panics if the limit is invalid, called when the endpoint using the limit is registered
+func (c *ConcurrencyLimit) validate() {
+	if c.Limit <= 0 {
+		panic("concurrency limit must be greater than 0")
+	}
+}
+
+func (c *ConcurrencyLimit) init() {
+	c.validate()
+	c.initOnce.Do(func() {
+		if c.QueueTimeout == 0 {
+			c.QueueTimeout = 5 * time.Second
+		}
+		if c.RetryAfter == 0 {
+			c.RetryAfter = time.Second
+		}
+		c.slots = make(chan struct{}, c.Limit)
+	})
+}
+
+// Returns `ok`, `rejected` (queue is full) or `timeout`, the slot has to be released only when the result is `ok`
+func (c *ConcurrencyLimit) acquire(ctx context.Context) string {
+	c.init()
+
+	select {
+	case c.slots <- struct{}{}:
+		return "ok"
+	default:
+	}
+
+	if c.waiting.Add(1) > int64(c.QueueSize) {
+		c.waiting.Add(-1)
+		return "rejected"
+	}
+	defer c.waiting.Add(-1)
+
+	timer := time.NewTimer(c.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.slots <- struct{}{}:
+		return "ok"
+	case <-timer.C:
+		return "timeout"
+	case <-ctx.Done():
+		return "timeout"
+	}
+}
+
+func (c *ConcurrencyLimit) release() {
+	<-c.slots
+}
+
+// Number of requests currently being handled
+func (c *ConcurrencyLimit) InFlight() int {
+	c.init()
+	return len(c.slots)
+}
+
+// Number of requests currently waiting in the queue
+func (c *ConcurrencyLimit) Waiting() int {
+	return int(c.waiting.Load())
+}
+
+func appendConcurrencyLimit(limits []*ConcurrencyLimit, limit *ConcurrencyLimit) []*ConcurrencyLimit {
+	if limit == nil {
+		return limits
+	}
+	return append(slices.Clip(limits), limit)
+}
+
+// AdaptiveConcurrencyLimit is a server-wide limit of requests handled at once, that adjusts itself based on
+// the observed latency. The limit grows while the latency stays close to the lowest observed latency, and
+// shrinks when the latency rises above it, shedding the load before the server gets overwhelmed.
+type AdaptiveConcurrencyLimit struct {
+	// Default: 100
+	InitialLimit int
+	// Default: 10
+	MinLimit int
+	// Default: 1000
+	MaxLimit int
+	// The limit is decreased when the average latency exceeds the lowest observed latency this many times
+	//
+	// Default: 2
+	LatencyTolerance float64
+	// Value of the Retry-After header of the 503 responses
+	//
+	// Default: 1 second
+	RetryAfter time.Duration
+
+	initOnce     sync.Once
+	mx           sync.Mutex
+	limit        float64
+	inFlight     int
+	avgLatency   float64
+	minLatency   float64
+	lastDecrease time.Time
+}
+
+func (a *AdaptiveConcurrencyLimit) init() {
+	a.initOnce.Do(func() {
+		if a.InitialLimit == 0 {
+			a.InitialLimit = 100
+		}
+		if a.MinLimit == 0 {
+			a.MinLimit = 10
+		}
+		if a.MaxLimit == 0 {
+			a.MaxLimit = 1000
+		}
+		if a.LatencyTolerance == 0 {
+			a.LatencyTolerance = 2
+		}
+		if a.RetryAfter == 0 {
+			a.RetryAfter = time.Second
+		}
+		a.limit = float64(a.InitialLimit)
+	})
+}
+
+func (a *AdaptiveConcurrencyLimit) acquire() bool {
+	a.init()
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if a.inFlight >= int(a.limit) {
+		return false
+	}
+	a.inFlight++
+	return true
+}
+
+func (a *AdaptiveConcurrencyLimit) release(latency time.Duration) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	inFlight := a.inFlight
+	a.inFlight--
+
+	sample := latency.Seconds()
+	if a.minLatency == 0 || sample < a.minLatency {
+		a.minLatency = sample
+	} else {
+		// let the baseline drift up slowly, so that it adapts when the handlers become slower permanently
+		a.minLatency *= 1.001
+	}
+	if a.avgLatency == 0 {
+		a.avgLatency = sample
+	} else {
+		a.avgLatency = a.avgLatency*0.9 + sample*0.1
+	}
+
+	if a.avgLatency > a.minLatency*a.LatencyTolerance {
+		// decrease at most once per average request duration, so that a single slow batch is not counted many times
+		if time.Since(a.lastDecrease).Seconds() > a.avgLatency {
+			a.limit = math.Max(float64(a.MinLimit), a.limit*0.9)
+			a.lastDecrease = time.Now()
+		}
+	} else if float64(inFlight) >= a.limit*0.8 {
+		a.limit = math.Min(float64(a.MaxLimit), a.limit+1/math.Max(1, math.Sqrt(a.limit)))
+	}
+}
+
+// Current value of the limit
+func (a *AdaptiveConcurrencyLimit) Limit() int {
+	a.init()
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	return int(a.limit)
+}
+
+func concurrencyLimitResponse(retryAfter time.Duration) *Response {
+	response := Respond.Problem(503, "the server is handling too many requests")
+	response.Headers.Set("Retry-After", fmt.Sprint(ceilSeconds(retryAfter)))
+	return response
+}
+
+// acquires the slots of the adaptive limit and all the concurrency limits, returns the function releasing them,
+// or the response that should be sent if the request was rejected
+func acquireConcurrencySlots(
+	request *Request,
+	adaptive *AdaptiveConcurrencyLimit,
+	limits []*ConcurrencyLimit,
+) (release func(), rejected *Response) {
+	request.monitorStart(MonitorStep.Queue, "")
+
+	acquired := make([]*ConcurrencyLimit, 0, len(limits))
+	releaseAll := func() {
+		for _, limit := range acquired {
+			limit.release()
+		}
+	}
+
+	for _, limit := range limits {
+		result := limit.acquire(request.Context())
+		if result != "ok" {
+			releaseAll()
+			request.monitorEndWithResult(MonitorStep.Queue, "", result)
+			return nil, concurrencyLimitResponse(limit.RetryAfter)
+		}
+		acquired = append(acquired, limit)
+	}
+
+	// the server-wide slot is taken only once the request leaves the queues of the endpoint, so that requests
+	// waiting for a busy endpoint do not hold the slots needed by the other endpoints
+	if adaptive != nil && !adaptive.acquire() {
+		releaseAll()
+		request.monitorEndWithResult(MonitorStep.Queue, "", "rejected")
+		return nil, concurrencyLimitResponse(adaptive.RetryAfter)
+	}
+
+	request.monitorEndWithResult(MonitorStep.Queue, "", "ok")
+
+	start := time.Now()
+	return func() {
+		releaseAll()
+		if adaptive != nil {
+			adaptive.release(time.Since(start))
+		}
+	}, nil
+}
@@ -0,0 +1,188 @@
+package butler_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	monitor := &recordingMonitor{}
+	server.Monitor(monitor)
+
+	unblock := make(chan struct{})
+	limit := &f.ConcurrencyLimit{
+		Limit:        1,
+		QueueSize:    1,
+		QueueTimeout: time.Second,
+		RetryAfter:   2 * time.Second,
+	}
+
+	group := &f.Group{Path: "/reports", MaxConcurrent: limit}
+	group.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/slow",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			<-unblock
+			return f.Respond.Ok()
+		},
+	})
+	group.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/fast",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+	server.Add(group)
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, resp := request("GET", baseUrl+"/reports/slow", nil)
+		assert.Equal(200, resp.StatusCode)
+	}()
+	waitUntil(func() bool { return limit.InFlight() == 1 })
+
+	// waits in the queue until the timeout
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, resp := request("GET", baseUrl+"/reports/fast", nil)
+		assert.Equal(503, resp.StatusCode)
+	}()
+	waitUntil(func() bool { return limit.Waiting() == 1 })
+
+	// queue is full
+	_, resp := request("GET", baseUrl+"/reports/fast", nil)
+	assert.Equal(503, resp.StatusCode)
+	assert.Equal("2", resp.Header.Get("Retry-After"))
+
+	// the queued request times out while the slow one is still running
+	time.Sleep(1200 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+
+	_, resp = request("GET", baseUrl+"/reports/fast", nil)
+	assert.Equal(200, resp.StatusCode)
+
+	waitUntil(func() bool { return len(monitor.Records()) == 4 })
+	results := map[string]int{}
+	for _, record := range monitor.Records() {
+		for _, step := range record.Steps {
+			if step.Step == f.MonitorStep.Queue {
+				results[step.Result]++
+			}
+		}
+	}
+	assert.Equal(map[string]int{"ok": 2, "rejected": 1, "timeout": 1}, results)
+}
+
+func TestAdaptiveConcurrencyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.AdaptiveConcurrency = &f.AdaptiveConcurrencyLimit{InitialLimit: 1, MinLimit: 1}
+
+	unblock := make(chan struct{})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/slow",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			<-unblock
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	done := make(chan int)
+	go func() {
+		_, resp := request("GET", baseUrl+"/slow", nil)
+		done <- resp.StatusCode
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	_, resp := request("GET", baseUrl+"/slow", nil)
+	assert.Equal(503, resp.StatusCode)
+	assert.Equal("1", resp.Header.Get("Retry-After"))
+
+	close(unblock)
+	assert.Equal(200, <-done)
+	assert.GreaterOrEqual(server.AdaptiveConcurrency.Limit(), 1)
+}
+
+func TestAdaptiveLimitWithQueuedRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.AdaptiveConcurrency = &f.AdaptiveConcurrencyLimit{InitialLimit: 2, MinLimit: 2, MaxLimit: 2}
+
+	unblock := make(chan struct{})
+	limit := &f.ConcurrencyLimit{Limit: 1, QueueSize: 1, QueueTimeout: 5 * time.Second}
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method:        "GET",
+		Path:          "/slow",
+		MaxConcurrent: limit,
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			<-unblock
+			return f.Respond.Ok()
+		},
+	})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/fast",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	done := make(chan int, 2)
+	go func() {
+		_, resp := request("GET", baseUrl+"/slow", nil)
+		done <- resp.StatusCode
+	}()
+	waitUntil(func() bool { return limit.InFlight() == 1 })
+	go func() {
+		_, resp := request("GET", baseUrl+"/slow", nil)
+		done <- resp.StatusCode
+	}()
+	waitUntil(func() bool { return limit.Waiting() == 1 })
+
+	// the queued request does not hold a server-wide slot
+	_, resp := request("GET", baseUrl+"/fast", nil)
+	assert.Equal(200, resp.StatusCode)
+
+	close(unblock)
+	assert.Equal(200, <-done)
+	assert.Equal(200, <-done)
+}
+
+func TestInvalidConcurrencyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.PanicsWithValue("concurrency limit must be greater than 0", func() {
+		f.CreateServer().Add(&f.BasicEndpoint[f.NoParams]{
+			Method:        "GET",
+			Path:          "/reports",
+			MaxConcurrent: &f.ConcurrencyLimit{QueueSize: 10},
+			Handler: func(request *f.Request, params f.NoParams) *f.Response {
+				return f.Respond.Ok()
+			},
+		})
+	})
+}
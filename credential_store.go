@@ -0,0 +1,251 @@
+package butler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Credential struct {
+	// Username for the basic auth, or the APIKeyID() of the key for the api key auth
+	ID string
+	// Hash of the password or the api key. Supported formats: bcrypt (`$2a$`, `$2b$`, `$2y$`),
+	// argon2id (`$argon2id$v=19$m=...,t=...,p=...$salt$hash`) and `sha256:<hex digest>`.
+	Hash string
+	// Principal attached to the authenticated requests. Defaults to the credential ID.
+	Principal any
+	// Roles granted to the authenticated requests
+	Roles []string
+	// Scopes granted to the authenticated requests
+	Scopes []string
+}
+
+// CredentialStore is used by the BasicAuth and APIKeyAuth handlers to look up the credentials.
+type CredentialStore interface {
+	// Returns the credential with the given ID, or nil if it does not exist
+	Lookup(ctx context.Context, id string) (*Credential, error)
+}
+
+// Returns the ID under which the given api key is stored in the CredentialStore.
+//
+// Keys are looked up by their SHA-256 digest, so the raw keys never need to be stored.
+func APIKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Hashes the password using bcrypt, the result can be used as the Credential.Hash
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Checks if the secret matches the hash, in constant time. Supports the same formats as Credential.Hash.
+func VerifySecret(secret string, hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(secret, hash)
+	case strings.HasPrefix(hash, "sha256:"):
+		expected, err := hex.DecodeString(strings.TrimPrefix(hash, "sha256:"))
+		if err != nil {
+			return false
+		}
+		sum := sha256.Sum256([]byte(secret))
+		return subtle.ConstantTimeCompare(sum[:], expected) == 1
+	}
+	return false
+}
+
+func verifyArgon2id(secret string, hash string) bool {
+	// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads)
+	if err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	actual := argon2.IDKey([]byte(secret), salt, iterations, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(actual, expected) == 1
+}
+
+// #region Memory Store
+
+// In-memory CredentialStore, safe for concurrent use.
+type MemoryCredentialStore struct {
+	mx          sync.RWMutex
+	credentials map[string]Credential
+}
+
+func NewMemoryCredentialStore(credentials ...Credential) *MemoryCredentialStore {
+	s := &MemoryCredentialStore{credentials: make(map[string]Credential, len(credentials))}
+	for _, c := range credentials {
+		s.credentials[c.ID] = c
+	}
+	return s
+}
+
+func (s *MemoryCredentialStore) Lookup(ctx context.Context, id string) (*Credential, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	c, ok := s.credentials[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// Adds or replaces the credential
+func (s *MemoryCredentialStore) Add(credential Credential) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.credentials[credential.ID] = credential
+}
+
+// Adds a basic auth user, the password is hashed with bcrypt
+func (s *MemoryCredentialStore) AddUser(username string, password string, roles ...string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	s.Add(Credential{ID: username, Hash: hash, Roles: roles})
+	return nil
+}
+
+// Adds an api key, the ID and Hash of the given credential are derived from the key
+func (s *MemoryCredentialStore) AddAPIKey(key string, credential Credential) {
+	credential.ID = APIKeyID(key)
+	credential.Hash = "sha256:" + credential.ID
+	s.Add(credential)
+}
+
+func (s *MemoryCredentialStore) Remove(id string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.credentials, id)
+}
+
+// #endregion Memory Store
+
+// #region Htpasswd Store
+
+// CredentialStore backed by a htpasswd file (`username:hash` per line). Only bcrypt (`htpasswd -B`) and
+// argon2id hashes are supported.
+//
+// The file is reloaded when it changes on the disk.
+type HtpasswdStore struct {
+	path     string
+	interval time.Duration
+
+	mx          sync.Mutex
+	credentials map[string]Credential
+	modTime     time.Time
+	lastCheck   time.Time
+}
+
+// Loads the htpasswd file, the file is checked for changes at most once per reloadInterval
+// (default 10 seconds)
+func NewHtpasswdStore(path string, reloadInterval ...time.Duration) (*HtpasswdStore, error) {
+	s := &HtpasswdStore{
+		path:     path,
+		interval: firstOr(reloadInterval, 10*time.Second),
+	}
+	err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *HtpasswdStore) Lookup(ctx context.Context, id string) (*Credential, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if time.Since(s.lastCheck) >= s.interval {
+		s.lastCheck = time.Now()
+		stat, err := os.Stat(s.path)
+		if err == nil && !stat.ModTime().Equal(s.modTime) {
+			// keep using the previous credentials if the new file cannot be loaded
+			s.load()
+		}
+	}
+
+	c, ok := s.credentials[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (s *HtpasswdStore) load() error {
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	credentials := map[string]Credential{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		username, hash, found := strings.Cut(line, ":")
+		if !found || username == "" || hash == "" {
+			return fmt.Errorf("invalid htpasswd entry at line %d", lineNo)
+		}
+		credentials[username] = Credential{ID: username, Hash: hash}
+	}
+
+	s.credentials = credentials
+	s.modTime = stat.ModTime()
+	s.lastCheck = time.Now()
+	return nil
+}
+
+// #endregion Htpasswd Store
@@ -0,0 +1,171 @@
+package butler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"path"
+	"slices"
+)
+
+type CSRFOptions struct {
+	// One of: `double-submit`, `session`
+	//
+	// `double-submit` keeps the token in a cookie, `session` keeps the token in the session (synchronizer token),
+	// which requires a session store to be configured on the server.
+	//
+	// Default: `double-submit`
+	Strategy string
+	// Name of the session the token is stored in, when using the `session` strategy
+	//
+	// Default: `session`
+	SessionName string
+	// Name of the cookie the token is stored in, when using the `double-submit` strategy
+	//
+	// Default: `_csrf`
+	CookieName string
+	// Default: `/`
+	CookiePath string
+	// Set to true to only send the token cookie over HTTPS
+	CookieSecure bool
+	// Default: http.SameSiteLaxMode
+	CookieSameSite http.SameSite
+	// Name of the request header the token is read from
+	//
+	// Default: `X-CSRF-Token`
+	HeaderName string
+	// Name of the form field the token is read from, when the header is not present
+	//
+	// Default: `_csrf`
+	FormField string
+	// Paths for which the token is not verified, supports the `path.Match` patterns (e.g. `/webhooks/*`)
+	SkipPaths []string
+}
+
+const csrfSessionKey = "_csrf_token"
+
+var csrfTokenKey = NewKey[string]("csrf_token")
+
+var csrfSafeMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE"}
+
+// CSRF creates a middleware that protects the unsafe methods (POST, PUT, PATCH, DELETE) against cross-site
+// request forgery. Requests without a valid token receive a 403 response.
+//
+// The token of the current request can be retrieved with request.CSRFToken() and included in the forms
+// or the request headers.
+func CSRF(opts ...CSRFOptions) Middleware {
+	o := firstOr(opts, CSRFOptions{})
+	if o.Strategy == "" {
+		o.Strategy = "double-submit"
+	}
+	if o.Strategy != "double-submit" && o.Strategy != "session" {
+		panic("invalid CSRF strategy: " + o.Strategy)
+	}
+	if o.SessionName == "" {
+		o.SessionName = "session"
+	}
+	if o.CookieName == "" {
+		o.CookieName = "_csrf"
+	}
+	if o.CookiePath == "" {
+		o.CookiePath = "/"
+	}
+	if o.CookieSameSite == 0 {
+		o.CookieSameSite = http.SameSiteLaxMode
+	}
+	if o.HeaderName == "" {
+		o.HeaderName = "X-CSRF-Token"
+	}
+	if o.FormField == "" {
+		o.FormField = "_csrf"
+	}
+
+	return Middleware{
+		Name: "csrf",
+		OnRequest: func(request *Request, respond func(response *Response)) error {
+			token, err := o.getOrCreateToken(request)
+			if err != nil {
+				return err
+			}
+			csrfTokenKey.Set(request, token)
+
+			if slices.Contains(csrfSafeMethods, request.Method) || o.isSkipped(request.Path) {
+				return nil
+			}
+
+			submitted := request.Headers.Get(o.HeaderName)
+			if submitted == "" {
+				submitted = request.FormValue(o.FormField)
+			}
+
+			if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				request.Logger.Debug("CSRF token is missing or invalid")
+				respond(Respond.Problem(403, "invalid CSRF token"))
+			}
+
+			return nil
+		},
+	}
+}
+
+// Returns the CSRF token of this request, or an empty string if the CSRF middleware is not used
+func (r *Request) CSRFToken() string {
+	return csrfTokenKey.GetOr(r, "")
+}
+
+func (o *CSRFOptions) getOrCreateToken(request *Request) (string, error) {
+	if o.Strategy == "session" {
+		s, err := request.Session(o.SessionName)
+		if err != nil {
+			return "", err
+		}
+
+		if token, ok := s.Values[csrfSessionKey].(string); ok && token != "" {
+			return token, nil
+		}
+
+		token, err := generateCSRFToken()
+		if err != nil {
+			return "", err
+		}
+		s.Values[csrfSessionKey] = token
+		return token, nil
+	}
+
+	cookie, err := request.GetCookie(o.CookieName)
+	if err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	token, err := generateCSRFToken()
+	if err != nil {
+		return "", err
+	}
+	request.EchoContext().SetCookie(&http.Cookie{
+		Name:     o.CookieName,
+		Value:    token,
+		Path:     o.CookiePath,
+		Secure:   o.CookieSecure,
+		SameSite: o.CookieSameSite,
+	})
+	return token, nil
+}
+
+func (o *CSRFOptions) isSkipped(requestPath string) bool {
+	for _, pattern := range o.SkipPaths {
+		if matched, _ := path.Match(pattern, requestPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func generateCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
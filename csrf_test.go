@@ -0,0 +1,92 @@
+package butler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func addCSRFEndpoints(server *f.Server) {
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/form",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text(request.CSRFToken())
+		},
+	})
+	for _, p := range []string{"/submit", "/webhooks/github"} {
+		server.Add(&f.BasicEndpoint[f.NoParams]{
+			Method: "POST",
+			Path:   p,
+			Handler: func(request *f.Request, params f.NoParams) *f.Response {
+				return f.Respond.Ok().Text("ok")
+			},
+		})
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	for _, strategy := range []string{"double-submit", "session"} {
+		t.Run(strategy, func(t *testing.T) {
+			assert := assert.New(t)
+
+			server := f.CreateServer()
+			if strategy == "session" {
+				server.SetSessionStore(sessions.NewCookieStore([]byte("secret")))
+			}
+			server.Use(f.CSRF(f.CSRFOptions{
+				Strategy:  strategy,
+				SkipPaths: []string{"/webhooks/*"},
+			}))
+			addCSRFEndpoints(server)
+
+			baseUrl := startServer(server)
+			defer server.Close()
+
+			jar, _ := cookiejar.New(nil)
+			client := &http.Client{Jar: jar}
+			do := func(method string, path string, body string, headers ...header) (int, string) {
+				req, err := http.NewRequest(method, baseUrl+path, strings.NewReader(body))
+				noErr(err)
+				if body != "" {
+					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				}
+				for _, h := range headers {
+					req.Header.Set(h.name, h.value)
+				}
+				resp, err := client.Do(req)
+				noErr(err)
+				b, _ := io.ReadAll(resp.Body)
+				return resp.StatusCode, string(b)
+			}
+
+			status, token := do("GET", "/form", "")
+			assert.Equal(200, status)
+			assert.NotEmpty(token)
+
+			// token stays the same for subsequent requests
+			_, sameToken := do("GET", "/form", "")
+			assert.Equal(token, sameToken)
+
+			status, _ = do("POST", "/submit", "")
+			assert.Equal(403, status)
+			status, _ = do("POST", "/submit", "", header{"X-CSRF-Token", "forged"})
+			assert.Equal(403, status)
+
+			status, _ = do("POST", "/submit", "", header{"X-CSRF-Token", token})
+			assert.Equal(200, status)
+			status, _ = do("POST", "/submit", url.Values{"_csrf": {token}}.Encode())
+			assert.Equal(200, status)
+
+			status, _ = do("POST", "/webhooks/github", "")
+			assert.Equal(200, status)
+		})
+	}
+}
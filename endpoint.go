@@ -1,6 +1,9 @@
 package butler
 
 import (
+	"reflect"
+	"time"
+
 	echo "github.com/labstack/echo/v4"
 )
 
@@ -8,6 +11,8 @@ type Endpoint[T any, B any] struct {
 	Method string
 	Path   string
 	Auth   AuthHandler
+	// Authorization rule that must be satisfied, in addition to the requirements of the parent groups
+	Require Requirement
 	// One of: `auto`, `none`, `gzip`, `brotli`, `deflate`
 	//
 	// Default: `auto`
@@ -17,6 +22,25 @@ type Endpoint[T any, B any] struct {
 	CachePolicy       *HttpCachePolicy
 	StreamingSettings *StreamingSettings
 	Handler           func(request *Request, params T, body *B) *Response
+	// Middlewares that will run only for this endpoint, after the middlewares of the parent groups
+	Middlewares []Middleware
+	// Maximum time the request handling can take. Once it passes, the request context gets cancelled and the
+	// client receives a 503 response. The timeout is cooperative: the handler is not interrupted and the 503 is
+	// sent once it returns, so the handler should stop when request.Context() is done. Overrides the timeout of
+	// the parent groups, set to a negative value to disable the timeout inherited from the parents.
+	Timeout time.Duration
+	// Limits how many requests can be handled at once by this endpoint, in addition to the limits of the parent groups
+	MaxConcurrent *ConcurrencyLimit
+	// Maximum size of the request body in bytes. Requests with larger bodies receive a 413 response. Overrides
+	// the limit of the parent groups, set to a negative value to disable the limit inherited from the parents.
+	MaxBodySize int64
+	// Optional. Body decoders keyed by the media type, added to (or overriding) the decoders of the server
+	BodyDecoders map[string]BodyDecoder
+	// Optional. Media types of the request body accepted by this endpoint, requests with other content types
+	// receive a 415 response. Every listed type must have a decoder.
+	//
+	// Default: all the types that have a decoder
+	AcceptContentTypes []string
 
 	Description string
 	Name        string
@@ -24,8 +48,13 @@ type Endpoint[T any, B any] struct {
 	// Optional. Type assigned to this field will be used to generate the response type in the documentation
 	ResponseType any
 
-	bindParams paramBinder[T]
-	parent     EndpointParent
+	bindParams     paramBinder[T]
+	validateParams *typeValidator
+	validateBody   *typeValidator
+	decoders       *bodyDecoders
+	parent         EndpointParent
+	// set by RestEndpoints for the endpoints operating on a single resource
+	skipListParams bool
 }
 
 func (e *Endpoint[T, B]) GetName() string {
@@ -52,6 +81,14 @@ func (e *Endpoint[T, B]) GetAuth() AuthHandler {
 	return e.Auth
 }
 
+func (e *Endpoint[T, B]) GetRequire() Requirement {
+	return e.Require
+}
+
+func (e *Endpoint[T, B]) GetRequirements() []Requirement {
+	return appendRequirement(e.parent.GetRequirements(), e.Require)
+}
+
 func (e *Endpoint[T, B]) GetEncoding() string {
 	return e.Encoding
 }
@@ -64,8 +101,24 @@ func (e *Endpoint[T, B]) GetStreamingSettings() *StreamingSettings {
 	return e.StreamingSettings
 }
 
+func (e *Endpoint[T, B]) GetMaxConcurrent() *ConcurrencyLimit {
+	return e.MaxConcurrent
+}
+
+func (e *Endpoint[T, B]) GetMaxBodySize() int64 {
+	return e.MaxBodySize
+}
+
+func (e *Endpoint[T, B]) GetTimeout() time.Duration {
+	return e.Timeout
+}
+
 func (e *Endpoint[T, B]) GetMiddlewares() []Middleware {
-	return []Middleware{}
+	return e.Middlewares
+}
+
+func (e *Endpoint[T, B]) Use(middleware Middleware) {
+	e.Middlewares = append(e.Middlewares, middleware)
 }
 
 func (e *Endpoint[T, B]) ExecuteHandler(ctx echo.Context, request *Request) (retVal *Response) {
@@ -73,10 +126,9 @@ func (e *Endpoint[T, B]) ExecuteHandler(ctx echo.Context, request *Request) (ret
 		e.bindParams = CreateSearchParamsBinder[T]()
 	}
 
-	body, err := e.parseBody(ctx)
-	if err != nil {
-		request.Logger.Error(err)
-		return Respond.BadRequest()
+	var body B
+	if resp := e.decoders.decode(request, &body); resp != nil {
+		return resp
 	}
 
 	params, perr := e.bindParams(ctx)
@@ -85,7 +137,15 @@ func (e *Endpoint[T, B]) ExecuteHandler(ctx echo.Context, request *Request) (ret
 		return perr.Response()
 	}
 
-	response := e.Handler(request, params, body)
+	var verr ValidationErrors
+	e.validateParams.run(&params, &verr)
+	e.validateBody.run(&body, &verr)
+	if len(verr) > 0 {
+		request.Logger.Error(verr.Error())
+		return verr.Response()
+	}
+
+	response := e.Handler(request, params, &body)
 	return response
 }
 
@@ -98,22 +158,34 @@ func (e *Endpoint[T, B]) Register(parent EndpointParent) {
 	}
 
 	e.parent = parent
+	e.bindParams = createParamsBinder[T](e.skipListParams)
+	e.validateParams = validatorFor(reflect.TypeFor[T]())
+	e.validateBody = validatorFor(reflect.TypeFor[B]())
+	// compiles the `file` tags of the body type, so that an invalid tag panics here rather than on the first upload
+	fileFieldsOf(reflect.TypeFor[B]())
+	e.decoders = resolveBodyDecoders(parent.GetServer(), e.BodyDecoders, e.AcceptContentTypes)
 	registerEndpoint(e, parent)
 }
 
-func (e *Endpoint[T, B]) parseBody(ctx echo.Context) (*B, error) {
-	var body B
-	err := ctx.Bind(&body)
-	return &body, err
-}
-
 //
 
+func (e *Endpoint[T, B]) skipsListParams() bool {
+	return e.skipListParams
+}
+
 func (g *Endpoint[T, B]) GetParamsT() any {
 	var zeroP T
 	return zeroP
 }
 
+// Media types of the request body accepted by the endpoint
+func (e *Endpoint[T, B]) GetAcceptContentTypes() []string {
+	if e.decoders == nil {
+		return nil
+	}
+	return e.decoders.contentTypes
+}
+
 func (g *Endpoint[T, B]) GetBodyT() any {
 	var zeroB B
 	return zeroB
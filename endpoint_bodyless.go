@@ -1,11 +1,18 @@
 package butler
 
-import echo "github.com/labstack/echo/v4"
+import (
+	"reflect"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
 
 type BasicEndpoint[T any] struct {
 	Method string
 	Path   string
 	Auth   AuthHandler
+	// Authorization rule that must be satisfied, in addition to the requirements of the parent groups
+	Require Requirement
 	// Specifies the Content Encoding that should be used for the endpoint responses
 	Encoding string
 	// CachePolicy is used to determine the value of the Cache-Control header and the server behavior
@@ -13,6 +20,18 @@ type BasicEndpoint[T any] struct {
 	CachePolicy       *HttpCachePolicy
 	StreamingSettings *StreamingSettings
 	Handler           func(request *Request, params T) *Response
+	// Middlewares that will run only for this endpoint, after the middlewares of the parent groups
+	Middlewares []Middleware
+	// Maximum time the request handling can take. Once it passes, the request context gets cancelled and the
+	// client receives a 503 response. The timeout is cooperative: the handler is not interrupted and the 503 is
+	// sent once it returns, so the handler should stop when request.Context() is done. Overrides the timeout of
+	// the parent groups, set to a negative value to disable the timeout inherited from the parents.
+	Timeout time.Duration
+	// Limits how many requests can be handled at once by this endpoint, in addition to the limits of the parent groups
+	MaxConcurrent *ConcurrencyLimit
+	// Maximum size of the request body in bytes. Requests with larger bodies receive a 413 response. Overrides
+	// the limit of the parent groups, set to a negative value to disable the limit inherited from the parents.
+	MaxBodySize int64
 
 	Description string
 	Name        string
@@ -20,8 +39,11 @@ type BasicEndpoint[T any] struct {
 	// Optional. Type assigned to this field will be used to generate the response type in the documentation
 	ResponseType any
 
-	bindParams paramBinder[T]
-	parent     EndpointParent
+	bindParams     paramBinder[T]
+	validateParams *typeValidator
+	parent         EndpointParent
+	// set by RestEndpoints for the endpoints operating on a single resource
+	skipListParams bool
 }
 
 func (e *BasicEndpoint[T]) GetName() string {
@@ -48,6 +70,14 @@ func (e *BasicEndpoint[T]) GetAuth() AuthHandler {
 	return e.Auth
 }
 
+func (e *BasicEndpoint[T]) GetRequire() Requirement {
+	return e.Require
+}
+
+func (e *BasicEndpoint[T]) GetRequirements() []Requirement {
+	return appendRequirement(e.parent.GetRequirements(), e.Require)
+}
+
 func (e *BasicEndpoint[T]) GetEncoding() string {
 	return e.Encoding
 }
@@ -60,8 +90,24 @@ func (e *BasicEndpoint[T]) GetStreamingSettings() *StreamingSettings {
 	return e.StreamingSettings
 }
 
+func (e *BasicEndpoint[T]) GetMaxConcurrent() *ConcurrencyLimit {
+	return e.MaxConcurrent
+}
+
+func (e *BasicEndpoint[T]) GetMaxBodySize() int64 {
+	return e.MaxBodySize
+}
+
+func (e *BasicEndpoint[T]) GetTimeout() time.Duration {
+	return e.Timeout
+}
+
 func (e *BasicEndpoint[T]) GetMiddlewares() []Middleware {
-	return []Middleware{}
+	return e.Middlewares
+}
+
+func (e *BasicEndpoint[T]) Use(middleware Middleware) {
+	e.Middlewares = append(e.Middlewares, middleware)
 }
 
 func (e *BasicEndpoint[T]) ExecuteHandler(ctx echo.Context, request *Request) (retVal *Response) {
@@ -75,6 +121,13 @@ func (e *BasicEndpoint[T]) ExecuteHandler(ctx echo.Context, request *Request) (r
 		return err.Response()
 	}
 
+	var verr ValidationErrors
+	e.validateParams.run(&params, &verr)
+	if len(verr) > 0 {
+		request.Logger.Error(verr.Error())
+		return verr.Response()
+	}
+
 	response := e.Handler(request, params)
 	return response
 }
@@ -88,12 +141,18 @@ func (e *BasicEndpoint[T]) Register(parent EndpointParent) {
 	}
 
 	e.parent = parent
+	e.bindParams = createParamsBinder[T](e.skipListParams)
+	e.validateParams = validatorFor(reflect.TypeFor[T]())
 
 	registerEndpoint(e, parent)
 }
 
 //
 
+func (e *BasicEndpoint[T]) skipsListParams() bool {
+	return e.skipListParams
+}
+
 func (g *BasicEndpoint[T]) GetParamsT() any {
 	var zeroP T
 	return zeroP
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	echo "github.com/labstack/echo/v4"
 )
@@ -14,6 +15,8 @@ type FsEndpoint struct {
 	Path string
 	Dir  string
 	Auth AuthHandler
+	// Authorization rule that must be satisfied, in addition to the requirements of the parent groups
+	Require Requirement
 	// Specifies the Content Encoding that should be used for the endpoint responses
 	Encoding string
 	// CachePolicy is used to determine the value of the Cache-Control header and the server behavior
@@ -21,6 +24,15 @@ type FsEndpoint struct {
 	CachePolicy       *HttpCachePolicy
 	StreamingSettings *StreamingSettings
 	DisableStreaming  bool
+	// Middlewares that will run only for this endpoint, after the middlewares of the parent groups
+	Middlewares []Middleware
+	// Maximum time the request handling can take. Once it passes, the request context gets cancelled and the
+	// client receives a 503 response. The timeout is cooperative: the handler is not interrupted and the 503 is
+	// sent once it returns, so the handler should stop when request.Context() is done. Overrides the timeout of
+	// the parent groups, set to a negative value to disable the timeout inherited from the parents.
+	Timeout time.Duration
+	// Limits how many requests can be handled at once by this endpoint, in addition to the limits of the parent groups
+	MaxConcurrent *ConcurrencyLimit
 	// Optional handler function
 	Handler func(
 		request *Request,
@@ -32,8 +44,7 @@ type FsEndpoint struct {
 	Description string
 	Name        string
 
-	middlewares []Middleware
-	parent      EndpointParent
+	parent EndpointParent
 }
 
 func (e *FsEndpoint) GetName() string {
@@ -61,6 +72,14 @@ func (e *FsEndpoint) GetAuth() AuthHandler {
 	return e.Auth
 }
 
+func (e *FsEndpoint) GetRequire() Requirement {
+	return e.Require
+}
+
+func (e *FsEndpoint) GetRequirements() []Requirement {
+	return appendRequirement(e.parent.GetRequirements(), e.Require)
+}
+
 func (e *FsEndpoint) GetEncoding() string {
 	return e.Encoding
 }
@@ -73,12 +92,25 @@ func (e *FsEndpoint) GetStreamingSettings() *StreamingSettings {
 	return e.StreamingSettings
 }
 
+func (e *FsEndpoint) GetMaxConcurrent() *ConcurrencyLimit {
+	return e.MaxConcurrent
+}
+
+// files are only served for GET requests, the limit of the parent groups is used
+func (e *FsEndpoint) GetMaxBodySize() int64 {
+	return 0
+}
+
+func (e *FsEndpoint) GetTimeout() time.Duration {
+	return e.Timeout
+}
+
 func (e *FsEndpoint) GetMiddlewares() []Middleware {
-	return e.middlewares
+	return e.Middlewares
 }
 
 func (e *FsEndpoint) Use(middleware Middleware) {
-	e.middlewares = append(e.middlewares, middleware)
+	e.Middlewares = append(e.Middlewares, middleware)
 }
 
 func (e *FsEndpoint) Register(parent EndpointParent) {
@@ -129,23 +161,23 @@ func (e *FsEndpoint) ExecuteHandler(ctx echo.Context, request *Request) (retVal
 	fullFilepath := path.Join(e.Dir, filepath)
 
 	if !fileExists(fullFilepath) {
-		return Respond.NotFound()
+		return Respond.Problem(404)
 	}
 
 	file, err := os.Open(fullFilepath)
 	if err != nil {
 		request.Logger.Error("failed to open file: ", fullFilepath)
-		return Respond.InternalError()
+		return Respond.Problem(500)
 	}
 
 	stat, err := file.Stat()
 	if err != nil {
 		request.Logger.Error("failed to get file stat: ", fullFilepath)
-		return Respond.InternalError()
+		return Respond.Problem(500)
 	}
 
 	if stat.IsDir() {
-		return Respond.NotFound()
+		return Respond.Problem(404)
 	}
 
 	resp := e.Handler(request, fullFilepath, file, stat)
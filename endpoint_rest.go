@@ -2,6 +2,8 @@ package butler
 
 import (
 	"reflect"
+	"slices"
+	"time"
 
 	echo "github.com/labstack/echo/v4"
 )
@@ -14,9 +16,21 @@ type RestResource[Q any, B any] interface {
 	Delete(req *Request, params Q) (responseOverride *Response)
 }
 
+type RestRequirements struct {
+	Get    Requirement
+	List   Requirement
+	Create Requirement
+	Update Requirement
+	Delete Requirement
+}
+
 type RestEndpoints[Q any, B any] struct {
 	Path string
 	Auth AuthHandler
+	// Authorization rule that must be satisfied for all of the rest endpoints
+	Require Requirement
+	// Additional authorization rules for specific operations, e.g. `RestRequirements{Delete: butler.Role("admin")}`
+	OperationRequirements RestRequirements
 	// One of: `auto`, `none`, `gzip`, `brotli`, `deflate`
 	//
 	// Default: `auto`
@@ -34,13 +48,22 @@ type RestEndpoints[Q any, B any] struct {
 	//
 	// value returned by this function (if not nil) will be passed to the Resource method instead
 	OnRequest func(requestType string, body *B) *B
+	// Middlewares that will run for all of the rest endpoints, after the middlewares of the parent groups
+	Middlewares []Middleware
+	// Maximum time the request handling can take, for every endpoint within the rest endpoints. Once it passes, the
+	// request context gets cancelled and the client receives a 503 response once the handler returns.
+	Timeout time.Duration
+	// Limits how many requests can be handled at once by all the endpoints within the rest endpoints combined
+	MaxConcurrent *ConcurrencyLimit
+	// Maximum size of the request body in bytes, for every endpoint within the rest endpoints. Requests with larger
+	// bodies receive a 413 response.
+	MaxBodySize int64
 
 	Description string
 	Name        string
 
-	middlewares []Middleware
-	parent      EndpointParent
-	routes      []EndpointInterface
+	parent EndpointParent
+	routes []EndpointInterface
 }
 
 func (g *RestEndpoints[T, B]) GetName() string {
@@ -60,7 +83,7 @@ func (g *RestEndpoints[T, B]) GetEcho() *echo.Echo {
 }
 
 func (g *RestEndpoints[T, B]) GetMiddlewares() []Middleware {
-	return append(g.parent.GetMiddlewares(), g.middlewares...)
+	return slices.Concat(g.parent.GetMiddlewares(), g.Middlewares)
 }
 
 func (g *RestEndpoints[T, B]) GetPath() string {
@@ -78,12 +101,34 @@ func (g *RestEndpoints[T, B]) GetAuthHandlers() []AuthHandler {
 	return append(g.parent.GetAuthHandlers(), g.Auth)
 }
 
+func (g *RestEndpoints[T, B]) GetRequirements() []Requirement {
+	return appendRequirement(g.parent.GetRequirements(), g.Require)
+}
+
+func (g *RestEndpoints[T, B]) GetConcurrencyLimits() []*ConcurrencyLimit {
+	return appendConcurrencyLimit(g.parent.GetConcurrencyLimits(), g.MaxConcurrent)
+}
+
+func (g *RestEndpoints[T, B]) GetTimeout() time.Duration {
+	if g.Timeout == 0 {
+		return g.parent.GetTimeout()
+	}
+	return g.Timeout
+}
+
+func (g *RestEndpoints[T, B]) GetMaxBodySize() int64 {
+	if g.MaxBodySize == 0 {
+		return g.parent.GetMaxBodySize()
+	}
+	return g.MaxBodySize
+}
+
 func (g *RestEndpoints[T, B]) GetServer() *Server {
 	return g.parent.GetServer()
 }
 
 func (g *RestEndpoints[T, B]) Use(middleware Middleware) {
-	g.middlewares = append(g.middlewares, middleware)
+	g.Middlewares = append(g.Middlewares, middleware)
 }
 
 func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
@@ -96,7 +141,9 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 	getEndpoint := &BasicEndpoint[T]{
 		Method:            "GET",
 		Path:              ":id",
+		skipListParams:    true,
 		Auth:              g.Auth,
+		Require:           g.OperationRequirements.Get,
 		Encoding:          g.Encoding,
 		CachePolicy:       g.CachePolicy,
 		StreamingSettings: g.StreamingSettings,
@@ -108,7 +155,7 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 			}
 
 			if payload == nil {
-				return Respond.NotFound()
+				return Respond.Problem(404)
 			}
 
 			if g.OnResponse != nil {
@@ -126,6 +173,7 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 		Method:            "GET",
 		Path:              "",
 		Auth:              g.Auth,
+		Require:           g.OperationRequirements.List,
 		Encoding:          g.Encoding,
 		CachePolicy:       g.CachePolicy,
 		StreamingSettings: g.StreamingSettings,
@@ -137,7 +185,7 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 			}
 
 			if payload == nil {
-				return Respond.BadRequest()
+				return Respond.Problem(400)
 			}
 
 			if g.OnResponse != nil {
@@ -164,6 +212,7 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 		Method:            "POST",
 		Path:              "",
 		Auth:              g.Auth,
+		Require:           g.OperationRequirements.Create,
 		Encoding:          g.Encoding,
 		StreamingSettings: g.StreamingSettings,
 		Handler: func(request *Request, params NoParams, body *B) *Response {
@@ -194,7 +243,9 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 	putEndpoint := &Endpoint[T, B]{
 		Method:            "PUT",
 		Path:              ":id",
+		skipListParams:    true,
 		Auth:              g.Auth,
+		Require:           g.OperationRequirements.Update,
 		Encoding:          g.Encoding,
 		StreamingSettings: g.StreamingSettings,
 		Handler: func(request *Request, params T, body *B) *Response {
@@ -225,7 +276,9 @@ func (g *RestEndpoints[T, B]) Register(server EndpointParent) {
 	deleteEndpoint := &BasicEndpoint[T]{
 		Method:            "DELETE",
 		Path:              ":id",
+		skipListParams:    true,
 		Auth:              g.Auth,
+		Require:           g.OperationRequirements.Delete,
 		Encoding:          g.Encoding,
 		StreamingSettings: g.StreamingSettings,
 		Handler: func(request *Request, params T) *Response {
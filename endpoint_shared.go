@@ -1,9 +1,13 @@
 package butler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
+	"slices"
+	"time"
 
 	echo "github.com/labstack/echo/v4"
 )
@@ -12,11 +16,15 @@ type AnyEndpoint interface {
 	GetPath() string
 	GetMethod() string
 	GetAuth() AuthHandler
+	GetRequire() Requirement
 	GetEncoding() string
 	ExecuteHandler(ctx echo.Context, request *Request) *Response
 	GetCachePolicy() *HttpCachePolicy
 	GetStreamingSettings() *StreamingSettings
 	GetMiddlewares() []Middleware
+	GetTimeout() time.Duration
+	GetMaxConcurrent() *ConcurrencyLimit
+	GetMaxBodySize() int64
 }
 
 func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
@@ -24,7 +32,8 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 	monitor := createMonitorRecorder(server)
 
 	echoServer := parent.GetEcho()
-	middlewares := append(parent.GetMiddlewares(), e.GetMiddlewares()...)
+	// parent middlewares always run first, the order in which they were added is preserved
+	middlewares := slices.Concat(parent.GetMiddlewares(), e.GetMiddlewares())
 	authHandlers := parent.GetAuthHandlers()
 	defaultEncoding := e.GetEncoding()
 	cachePolicy := e.GetCachePolicy()
@@ -32,8 +41,25 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 	fullpath := e.GetPath()
 	method := e.GetMethod()
 
+	timeout := e.GetTimeout()
+	if timeout == 0 {
+		timeout = parent.GetTimeout()
+	}
+
+	maxBodySize := e.GetMaxBodySize()
+	if maxBodySize == 0 {
+		maxBodySize = parent.GetMaxBodySize()
+	}
+
 	reqMiddlewares := getReqMiddlewares(middlewares)
 	respMiddlewares := getRespMiddlewares(middlewares)
+	aroundMiddlewares := getAroundMiddlewares(middlewares)
+
+	requirements := appendRequirement(parent.GetRequirements(), e.GetRequire())
+	concurrencyLimits := appendConcurrencyLimit(parent.GetConcurrencyLimits(), e.GetMaxConcurrent())
+	for _, limit := range concurrencyLimits {
+		limit.validate()
+	}
 
 	endpAuth := e.GetAuth()
 	if endpAuth != nil {
@@ -45,8 +71,16 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 	}
 
 	handler := func(ctx echo.Context) error {
+		if timeout > 0 {
+			timeoutCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+			defer cancel()
+			ctx.SetRequest(ctx.Request().WithContext(timeoutCtx))
+		}
+
 		request := NewRequest(ctx, monitor)
+		request.server = server
 		defer request.completeMonitor()
+		defer request.runAfterResponseHooks()
 
 		defer func() {
 			if r := recover(); r != nil {
@@ -69,25 +103,45 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 
 				request.saveSessions()
 
-				if err != nil {
-					ctx.Error(err)
-				} else {
-					ctx.NoContent(500)
+				if !ctx.Response().Committed {
+					Respond.Problem(500).send(request)
 				}
 			}
 		}()
 
+		if tooLarge := limitRequestBody(request, maxBodySize); tooLarge != nil {
+			return tooLarge.send(request)
+		}
+
 		if len(authHandlers) > 0 {
 			request.monitorStart(MonitorStep.Auth, "")
 
 			for _, authHandler := range authHandlers {
 				auth := authHandler(request)
 				if !auth.IsSuccessful() {
+					request.monitorEndWithResult(MonitorStep.Auth, "", auth.Result())
 					return auth.SendResponse(request)
 				}
+				if auth.principal != nil {
+					request.principal = auth.principal
+				}
+				request.scopes = append(request.scopes, auth.scopes...)
+				request.roles = append(request.roles, auth.roles...)
 			}
 
-			request.monitorEnd(MonitorStep.Auth, "")
+			request.monitorEndWithResult(MonitorStep.Auth, "", "ok")
+		}
+
+		if len(requirements) > 0 {
+			request.monitorStart(MonitorStep.Authorization, "")
+
+			failed := checkRequirements(request, requirements)
+			if failed != nil {
+				request.monitorEndWithResult(MonitorStep.Authorization, "", failed.Result())
+				return failed.SendResponse(request)
+			}
+
+			request.monitorEndWithResult(MonitorStep.Authorization, "", "ok")
 		}
 
 		var response *Response
@@ -105,7 +159,7 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 
 			if err != nil {
 				request.Logger.Errorf("middleware %s request handler returned an error", md.Name)
-				response = Respond.InternalError()
+				response = Respond.Problem(500)
 				return response.send(request)
 			}
 
@@ -114,10 +168,36 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 			}
 		}
 
+		if response == nil && (server.AdaptiveConcurrency != nil || len(concurrencyLimits) > 0) {
+			release, rejected := acquireConcurrencySlots(request, server.AdaptiveConcurrency, concurrencyLimits)
+			if rejected != nil {
+				response = rejected
+			} else {
+				defer release()
+			}
+		}
+
 		if response == nil {
-			request.monitorStart(MonitorStep.Handler, "")
-			response = e.ExecuteHandler(ctx, request)
-			request.monitorEnd(MonitorStep.Handler, "")
+			handler := func() *Response {
+				request.monitorStart(MonitorStep.Handler, "")
+				defer request.monitorEnd(MonitorStep.Handler, "")
+				return e.ExecuteHandler(ctx, request)
+			}
+
+			if len(aroundMiddlewares) > 0 {
+				handler = composeAroundMiddlewares(aroundMiddlewares, request, handler)
+			}
+
+			response = handler()
+
+			if request.bodyTooLarge {
+				response = Respond.Problem(413, "the request body is too large")
+			}
+
+			if errors.Is(request.Context().Err(), context.DeadlineExceeded) {
+				request.Logger.Errorf("request handling exceeded the timeout of %v", timeout)
+				response = Respond.Problem(503, "the request handling took too long")
+			}
 		}
 
 		for _, md := range respMiddlewares {
@@ -135,14 +215,14 @@ func registerEndpoint[E AnyEndpoint](e E, parent EndpointParent) {
 
 			if err != nil {
 				request.Logger.Errorf("middleware %s response handler returned an error", md.Name)
-				response = Respond.InternalError()
+				response = Respond.Problem(500)
 				return response.send(request)
 			}
 		}
 
 		if response == nil {
 			request.Logger.Errorf("endpoint handler did not return a response [path=%s]", fullpath)
-			response = Respond.InternalError()
+			response = Respond.Problem(500)
 			return response.send(request)
 		}
 
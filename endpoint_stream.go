@@ -0,0 +1,173 @@
+package butler
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// StreamEndpoint is an endpoint that gives the handler direct access to the raw request body, instead of
+// binding it to a struct. Useful for large uploads that should not be loaded into memory at once.
+type StreamEndpoint[T any] struct {
+	Method string
+	Path   string
+	Auth   AuthHandler
+	// Authorization rule that must be satisfied, in addition to the requirements of the parent groups
+	Require Requirement
+	// One of: `auto`, `none`, `gzip`, `brotli`, `deflate`
+	//
+	// Default: `auto`
+	Encoding string
+	// CachePolicy is used to determine the value of the Cache-Control header and the server behavior
+	// when receiving a request with a If-None-Match header.
+	CachePolicy       *HttpCachePolicy
+	StreamingSettings *StreamingSettings
+	// Reading the body past the MaxBodySize limit returns an error (see IsBodyTooLarge), in which case
+	// the client receives a 413 response regardless of the response returned by the handler.
+	Handler func(request *Request, params T, body io.Reader) *Response
+	// Middlewares that will run only for this endpoint, after the middlewares of the parent groups
+	Middlewares []Middleware
+	// Maximum time the request handling can take. Once it passes, the request context gets cancelled and the
+	// client receives a 503 response. The timeout is cooperative: the handler is not interrupted and the 503 is
+	// sent once it returns, so the handler should stop when request.Context() is done. Overrides the timeout of
+	// the parent groups, set to a negative value to disable the timeout inherited from the parents.
+	Timeout time.Duration
+	// Limits how many requests can be handled at once by this endpoint, in addition to the limits of the parent groups
+	MaxConcurrent *ConcurrencyLimit
+	// Maximum size of the request body in bytes. Requests with larger bodies receive a 413 response. Overrides
+	// the limit of the parent groups, set to a negative value to disable the limit inherited from the parents.
+	MaxBodySize int64
+
+	Description string
+	Name        string
+
+	// Optional. Type assigned to this field will be used to generate the response type in the documentation
+	ResponseType any
+
+	bindParams     paramBinder[T]
+	validateParams *typeValidator
+	parent         EndpointParent
+}
+
+func (e *StreamEndpoint[T]) GetName() string {
+	return e.Name
+}
+
+func (e *StreamEndpoint[T]) GetDescription() string {
+	return e.Description
+}
+
+func (e *StreamEndpoint[T]) GetSubRoutes() []EndpointInterface {
+	return []EndpointInterface{}
+}
+
+func (e *StreamEndpoint[T]) GetPath() string {
+	return pathJoin(e.parent.GetPath(), e.Path)
+}
+
+func (e *StreamEndpoint[T]) GetMethod() string {
+	return e.Method
+}
+
+func (e *StreamEndpoint[T]) GetAuth() AuthHandler {
+	return e.Auth
+}
+
+func (e *StreamEndpoint[T]) GetRequire() Requirement {
+	return e.Require
+}
+
+func (e *StreamEndpoint[T]) GetRequirements() []Requirement {
+	return appendRequirement(e.parent.GetRequirements(), e.Require)
+}
+
+func (e *StreamEndpoint[T]) GetEncoding() string {
+	return e.Encoding
+}
+
+func (e *StreamEndpoint[T]) GetCachePolicy() *HttpCachePolicy {
+	return e.CachePolicy
+}
+
+func (e *StreamEndpoint[T]) GetStreamingSettings() *StreamingSettings {
+	return e.StreamingSettings
+}
+
+func (e *StreamEndpoint[T]) GetMaxConcurrent() *ConcurrencyLimit {
+	return e.MaxConcurrent
+}
+
+func (e *StreamEndpoint[T]) GetMaxBodySize() int64 {
+	return e.MaxBodySize
+}
+
+func (e *StreamEndpoint[T]) GetTimeout() time.Duration {
+	return e.Timeout
+}
+
+func (e *StreamEndpoint[T]) GetMiddlewares() []Middleware {
+	return e.Middlewares
+}
+
+func (e *StreamEndpoint[T]) Use(middleware Middleware) {
+	e.Middlewares = append(e.Middlewares, middleware)
+}
+
+func (e *StreamEndpoint[T]) ExecuteHandler(ctx echo.Context, request *Request) (retVal *Response) {
+	if e.bindParams == nil {
+		e.bindParams = CreateSearchParamsBinder[T]()
+	}
+
+	params, err := e.bindParams(ctx)
+	if err != nil {
+		request.Logger.Error(err.ToString())
+		return err.Response()
+	}
+
+	var verr ValidationErrors
+	e.validateParams.run(&params, &verr)
+	if len(verr) > 0 {
+		request.Logger.Error(verr.Error())
+		return verr.Response()
+	}
+
+	var body io.Reader = ctx.Request().Body
+	if body == nil {
+		body = http.NoBody
+	}
+
+	response := e.Handler(request, params, body)
+	return response
+}
+
+func (e *StreamEndpoint[T]) Register(parent EndpointParent) {
+	if e.Handler == nil {
+		panic("endpoint has no handler")
+	}
+	if e.parent != nil {
+		panic("endpoint can only be registered once")
+	}
+
+	e.parent = parent
+	e.bindParams = CreateSearchParamsBinder[T]()
+	e.validateParams = validatorFor(reflect.TypeFor[T]())
+	registerEndpoint(e, parent)
+}
+
+//
+
+func (g *StreamEndpoint[T]) GetParamsT() any {
+	var zeroP T
+	return zeroP
+}
+
+func (g *StreamEndpoint[T]) GetBodyT() any {
+	return nil
+}
+
+func (g *StreamEndpoint[T]) GetResponseT() any {
+	return g.ResponseType
+}
@@ -1,14 +1,32 @@
 package butler
 
-import echo "github.com/labstack/echo/v4"
+import (
+	"slices"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
 
 type Group struct {
 	Path string
 	Auth AuthHandler
-
-	routes      []EndpointInterface
-	middlewares []Middleware
-	parent      EndpointParent
+	// Authorization rule that must be satisfied for every endpoint within the group, e.g.
+	// `butler.AnyOf(butler.Role("admin"), butler.Scope("orders:write"))`
+	Require Requirement
+	// Middlewares that will run for every endpoint within the group, after the middlewares of the parent groups
+	Middlewares []Middleware
+	// Maximum time the request handling can take, for every endpoint within the group. Once it passes, the
+	// request context gets cancelled and the client receives a 503 response once the handler returns.
+	Timeout time.Duration
+	// Limits how many requests can be handled at once by all the endpoints within the group combined
+	MaxConcurrent *ConcurrencyLimit
+	// Maximum size of the request body in bytes, for every endpoint within the group. Requests with larger
+	// bodies receive a 413 response. The limit is read when an endpoint is added, it must be set before
+	// calling Add().
+	MaxBodySize int64
+
+	routes []EndpointInterface
+	parent EndpointParent
 
 	Name        string
 	Description string
@@ -19,7 +37,7 @@ func (g *Group) GetEcho() *echo.Echo {
 }
 
 func (g *Group) GetMiddlewares() []Middleware {
-	return append(g.parent.GetMiddlewares(), g.middlewares...)
+	return slices.Concat(g.parent.GetMiddlewares(), g.Middlewares)
 }
 
 func (g *Group) GetPath() string {
@@ -37,6 +55,28 @@ func (g *Group) GetAuthHandlers() []AuthHandler {
 	return append(g.parent.GetAuthHandlers(), g.Auth)
 }
 
+func (g *Group) GetRequirements() []Requirement {
+	return appendRequirement(g.parent.GetRequirements(), g.Require)
+}
+
+func (g *Group) GetConcurrencyLimits() []*ConcurrencyLimit {
+	return appendConcurrencyLimit(g.parent.GetConcurrencyLimits(), g.MaxConcurrent)
+}
+
+func (g *Group) GetTimeout() time.Duration {
+	if g.Timeout == 0 {
+		return g.parent.GetTimeout()
+	}
+	return g.Timeout
+}
+
+func (g *Group) GetMaxBodySize() int64 {
+	if g.MaxBodySize == 0 {
+		return g.parent.GetMaxBodySize()
+	}
+	return g.MaxBodySize
+}
+
 func (g *Group) GetServer() *Server {
 	return g.parent.GetServer()
 }
@@ -58,7 +98,7 @@ func (g *Group) Add(endpoint EndpointInterface) {
 }
 
 func (g *Group) Use(middleware Middleware) {
-	g.middlewares = append(g.middlewares, middleware)
+	g.Middlewares = append(g.Middlewares, middleware)
 }
 
 func (g *Group) Register(server EndpointParent) {
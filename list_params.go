@@ -0,0 +1,492 @@
+package butler
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ncpa0cpl/butler/swag"
+)
+
+// implemented by the params that are only meaningful for the endpoints returning lists (pagination, sorting,
+// filtering), RestEndpoints does not bind them for the Show, Update and Delete endpoints
+type listParam interface {
+	isListParam()
+}
+
+// implemented by the params that are configured with their own struct tags
+type configurableParam interface {
+	configure(tag reflect.StructTag)
+}
+
+func queryParamValues(ctx RequestContext, name string) []string {
+	if c, ok := ctx.(interface{ QueryParams() url.Values }); ok {
+		return c.QueryParams()[name]
+	}
+	if v := ctx.QueryParam(name); v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
+// #region Pagination
+
+const (
+	defaultPerPage    = 20
+	defaultMaxPerPage = 100
+)
+
+// Reads the `page` and `per_page` query params. Pages are numbered starting from 1.
+//
+// The page size can be configured with the `pagination` struct tag, e.g. `pagination:"per_page=50,max=200"`
+//
+// Default: 20 items per page, at most 100
+type Pagination struct {
+	page       int64
+	perPage    int64
+	maxPerPage int64
+	isSet      bool
+}
+
+func (p *Pagination) isListParam() {}
+
+func (p *Pagination) configure(tag reflect.StructTag) {
+	p.perPage = defaultPerPage
+	p.maxPerPage = defaultMaxPerPage
+
+	for option := range strings.SplitSeq(tag.Get("pagination"), ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(option, "=")
+		num, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || num < 1 {
+			panic(fmt.Sprintf("invalid pagination tag: %q must be a positive integer", option))
+		}
+
+		switch key {
+		case "per_page":
+			p.perPage = num
+		case "max":
+			p.maxPerPage = num
+		default:
+			panic(fmt.Sprintf("invalid pagination tag: unknown option %q", key))
+		}
+	}
+
+	if p.perPage > p.maxPerPage {
+		panic("invalid pagination tag: per_page cannot be greater than max")
+	}
+}
+
+func (p *Pagination) DocumentParams(name string, tag reflect.StructTag) []swag.TypeStructure {
+	var config Pagination
+	config.configure(tag)
+
+	return []swag.TypeStructure{
+		{Name: "page", Kind: reflect.Int64.String(), In: "query", Default: "1", Rules: "min=1", ListOnly: true},
+		{
+			Name:     "per_page",
+			Kind:     reflect.Int64.String(),
+			In:       "query",
+			Default:  strconv.FormatInt(config.perPage, 10),
+			Rules:    fmt.Sprintf("min=1,max=%d", config.maxPerPage),
+			ListOnly: true,
+		},
+	}
+}
+
+// True if the request contained the `page` or the `per_page` param
+func (p *Pagination) Has() bool {
+	return p.isSet
+}
+
+// Number of the requested page, starting from 1
+func (p *Pagination) Page() int64 {
+	return max(p.page, 1)
+}
+
+// Number of items per page
+func (p *Pagination) PerPage() int64 {
+	if p.perPage == 0 {
+		return defaultPerPage
+	}
+	return p.perPage
+}
+
+// Number of items preceding the requested page
+func (p *Pagination) Offset() int64 {
+	return (p.Page() - 1) * p.PerPage()
+}
+
+func (p *Pagination) Init(ctx RequestContext, name string) *ParamParsingError {
+	if v := ctx.QueryParam("page"); v != "" {
+		page, err := parsePositiveParam(v, "page")
+		if err != nil {
+			return err
+		}
+		p.page = page
+		p.isSet = true
+	}
+
+	if v := ctx.QueryParam("per_page"); v != "" {
+		perPage, err := parsePositiveParam(v, "per_page")
+		if err != nil {
+			return err
+		}
+		if perPage > p.maxPerPage {
+			err := invalidParamValue(fmt.Sprintf("expected at most %d, got %d", p.maxPerPage, perPage))
+			err.paramName = "per_page"
+			return err
+		}
+		p.perPage = perPage
+		p.isSet = true
+	}
+
+	return nil
+}
+
+func parsePositiveParam(value string, paramName string) (int64, *ParamParsingError) {
+	num, err := parseIntParam(value)
+	if err == nil && num < 1 {
+		err = invalidParamValue(fmt.Sprintf("expected a positive integer, got %d", num))
+	}
+	if err != nil {
+		err.paramName = paramName
+		return 0, err
+	}
+	return num, nil
+}
+
+// #endregion Pagination
+
+// #region Sort
+
+type SortField[T any] struct {
+	Field T
+	Desc  bool
+}
+
+// Comma-separated list of fields to sort by, fields prefixed with `-` are sorted in descending order,
+// e.g. `?sort=-created,name`. The allowed fields are the values of the T enum.
+type Sort[T Enum[T]] struct {
+	value []SortField[T]
+	isSet bool
+}
+
+func (p *Sort[T]) isListParam() {}
+
+func (p *Sort[T]) DocumentParams(name string, tag reflect.StructTag) []swag.TypeStructure {
+	return []swag.TypeStructure{{
+		Name:     name,
+		Kind:     "sort: " + enumValuesList[T]() + " (prefix with - for descending order)",
+		In:       "query",
+		Default:  tag.Get("default"),
+		ListOnly: true,
+	}}
+}
+
+// True if the request contained this param
+func (p *Sort[T]) Has() bool {
+	return p.isSet
+}
+
+func (p *Sort[T]) Get(defaultValue ...SortField[T]) []SortField[T] {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue
+	}
+	return p.value
+}
+
+// Parses a single, comma-separated value and appends the fields to the sort order
+func (p *Sort[T]) Set(value string) *ParamParsingError {
+	var zero T
+	allowed := zero.Values()
+
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		field, desc := strings.CutPrefix(item, "-")
+		if !slices.Contains(allowed, T(field)) {
+			return invalidParamValue(fmt.Sprintf("cannot sort by %q, expected one of: %s", field, enumValuesList[T]()))
+		}
+
+		p.value = append(p.value, SortField[T]{Field: T(field), Desc: desc})
+	}
+
+	p.isSet = true
+	return nil
+}
+
+func (p *Sort[T]) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *Sort[T]) Init(ctx RequestContext, name string) *ParamParsingError {
+	for _, v := range queryParamValues(ctx, name) {
+		if err := p.Set(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// #endregion Sort
+
+// #region Filter
+
+type FilterOperator string
+
+const (
+	FilterEq       FilterOperator = "eq"
+	FilterNe       FilterOperator = "ne"
+	FilterGt       FilterOperator = "gt"
+	FilterLt       FilterOperator = "lt"
+	FilterIn       FilterOperator = "in"
+	FilterContains FilterOperator = "contains"
+)
+
+var filterOperators = []FilterOperator{FilterEq, FilterNe, FilterGt, FilterLt, FilterIn, FilterContains}
+
+type FilterCondition struct {
+	// Path of the filtered field, e.g. `status` or `author.name` for the fields of nested structs
+	Field    string
+	Operator FilterOperator
+	// Values parsed by the param type of the field, only the `in` operator can have more than one value
+	Values []any
+}
+
+// First of the condition values
+func (c FilterCondition) Value() any {
+	return c.Values[0]
+}
+
+type filterField struct {
+	path      string
+	kind      string
+	operators []FilterOperator
+	newParam  func() optionalParam
+}
+
+var filterFieldsCache sync.Map
+
+// Returns the fields that can be filtered by, panics if the T struct or any of its `filter` tags is invalid
+func filterFieldsOf(t reflect.Type) []filterField {
+	if fields, ok := filterFieldsCache.Load(t); ok {
+		return fields.([]filterField)
+	}
+	fields := collectFilterFields(t, "", nil)
+	filterFieldsCache.Store(t, fields)
+	return fields
+}
+
+// visited holds the structs containing t, nested structs referencing one of them would never end
+func collectFilterFields(t reflect.Type, prefix string, visited []reflect.Type) []filterField {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("filter type must be a struct, got %s", t))
+	}
+	if slices.Contains(visited, t) {
+		panic(fmt.Sprintf("filter type %s is recursive", t))
+	}
+	visited = append(visited, t)
+
+	var fields []filterField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		path := prefix + paramNameOf(field)
+
+		if !field.Type.Implements(optionalParamInterface) {
+			if field.Tag.Get("filter") != "nested" {
+				panic(fmt.Sprintf(
+					"invalid filter field %s: must be a param type, or a struct with the `filter:\"nested\"` tag", field.Name,
+				))
+			}
+			// nested struct, its fields are filtered by the dot separated path, e.g. `filter[author.name]`
+			fields = append(fields, collectFilterFields(field.Type, path+".", visited)...)
+			continue
+		}
+
+		operators := []FilterOperator{FilterEq}
+		if tag := field.Tag.Get("filter"); tag != "" {
+			operators = operators[:0]
+			for op := range strings.SplitSeq(tag, ",") {
+				op := FilterOperator(strings.TrimSpace(op))
+				if !slices.Contains(filterOperators, op) {
+					panic(fmt.Sprintf("invalid filter tag on field %s: unknown operator %q", field.Name, op))
+				}
+				operators = append(operators, op)
+			}
+		}
+
+		elem := field.Type.Elem()
+		fields = append(fields, filterField{
+			path:      path,
+			kind:      reflect.New(elem).Interface().(interface{ AcceptedKind() string }).AcceptedKind(),
+			operators: operators,
+			newParam: func() optionalParam {
+				return reflect.New(elem).Interface().(optionalParam)
+			},
+		})
+	}
+
+	return fields
+}
+
+// Filters described by the fields of the T struct, each field must be a param type (which is used to parse the
+// filter values) or a nested struct with the `filter:"nested"` tag. Filters are passed as `?filter[field]=value` or with an explicit operator
+// `?filter[field][op]=value`, the `in` operator accepts a comma-separated list of values.
+//
+// Allowed operators are listed in the `filter` struct tag of the field, e.g. `filter:"eq,in"`
+//
+// Default: only `eq` is allowed
+type Filter[T any] struct {
+	conditions []FilterCondition
+}
+
+func (p *Filter[T]) isListParam() {}
+
+func (p *Filter[T]) configure(tag reflect.StructTag) {
+	filterFieldsOf(reflect.TypeFor[T]())
+}
+
+func (p *Filter[T]) DocumentParams(name string, tag reflect.StructTag) []swag.TypeStructure {
+	fields := filterFieldsOf(reflect.TypeFor[T]())
+	params := make([]swag.TypeStructure, 0, len(fields))
+	for _, field := range fields {
+		operators := make([]string, 0, len(field.operators))
+		for _, op := range field.operators {
+			operators = append(operators, string(op))
+		}
+		params = append(params, swag.TypeStructure{
+			Name:     fmt.Sprintf("%s[%s]", name, field.path),
+			Kind:     field.kind,
+			In:       "query",
+			Rules:    "operators: " + strings.Join(operators, ", "),
+			ListOnly: true,
+		})
+	}
+	return params
+}
+
+// True if the request contained any filters
+func (p *Filter[T]) Has() bool {
+	return len(p.conditions) > 0
+}
+
+// All the filters of the request, ordered by the field path
+func (p *Filter[T]) Conditions() []FilterCondition {
+	return p.conditions
+}
+
+// Filters applied to the field with the given path
+func (p *Filter[T]) Field(path string) []FilterCondition {
+	var conditions []FilterCondition
+	for _, c := range p.conditions {
+		if c.Field == path {
+			conditions = append(conditions, c)
+		}
+	}
+	return conditions
+}
+
+func (p *Filter[T]) Init(ctx RequestContext, name string) *ParamParsingError {
+	c, ok := ctx.(interface{ QueryParams() url.Values })
+	if !ok {
+		return nil
+	}
+
+	query := c.QueryParams()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if strings.HasPrefix(key, name+"[") {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	fields := filterFieldsOf(reflect.TypeFor[T]())
+
+	for _, key := range keys {
+		path, op, ok := parseFilterKey(strings.TrimPrefix(key, name))
+		if !ok {
+			return filterError(key, "expected the filter[field] or filter[field][operator] format")
+		}
+
+		idx := slices.IndexFunc(fields, func(f filterField) bool { return f.path == path })
+		if idx == -1 {
+			return filterError(key, fmt.Sprintf("cannot filter by %q", path))
+		}
+		field := fields[idx]
+
+		if !slices.Contains(field.operators, op) {
+			return filterError(key, fmt.Sprintf("operator %q is not allowed for this field", op))
+		}
+
+		for _, raw := range query[key] {
+			values := []string{raw}
+			if op == FilterIn {
+				values = strings.Split(raw, ",")
+			}
+
+			condition := FilterCondition{Field: path, Operator: op}
+			for _, v := range values {
+				param := field.newParam()
+				if err := param.Set(strings.TrimSpace(v)); err != nil {
+					err.paramName = key
+					return err
+				}
+				value, _ := param.paramValue()
+				condition.Values = append(condition.Values, value)
+			}
+			p.conditions = append(p.conditions, condition)
+		}
+	}
+
+	return nil
+}
+
+// splits `[field]` or `[field][op]` into the field path and the operator
+func parseFilterKey(key string) (path string, op FilterOperator, ok bool) {
+	rest, found := strings.CutPrefix(key, "[")
+	if !found {
+		return "", "", false
+	}
+	path, rest, found = strings.Cut(rest, "]")
+	if !found || path == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return path, FilterEq, true
+	}
+
+	rest, found = strings.CutPrefix(rest, "[")
+	opName, rest, closed := strings.Cut(rest, "]")
+	if !found || !closed || rest != "" {
+		return "", "", false
+	}
+	return path, FilterOperator(opName), true
+}
+
+func filterError(key string, message string) *ParamParsingError {
+	err := invalidParamValue(message)
+	err.paramName = key
+	return err
+}
+
+// #endregion Filter
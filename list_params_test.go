@@ -0,0 +1,224 @@
+package butler_test
+
+import (
+	"net/url"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/ncpa0cpl/butler/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+type articleSortField string
+
+func (articleSortField) Values() []articleSortField {
+	return []articleSortField{"created", "title"}
+}
+
+type articleFilter struct {
+	Status *f.StringQParam `filter:"eq,in"`
+	Likes  *f.NumberQParam `filter:"gt,lt"`
+	Title  *f.StringQParam `filter:"contains"`
+	Author struct {
+		Name *f.StringQParam
+	} `filter:"nested"`
+}
+
+type articleParams struct {
+	ID     *f.StringUrlParam
+	Page   *f.Pagination `pagination:"per_page=10,max=50"`
+	Sort   *f.Sort[articleSortField]
+	Filter *f.Filter[articleFilter]
+}
+
+type article struct {
+	ID    string
+	Title string
+}
+
+type articleResource struct{}
+
+func (articleResource) Get(req *f.Request, params articleParams) (*article, *f.Response) {
+	return &article{ID: params.ID.Get(), Title: "Article"}, nil
+}
+
+func (articleResource) List(req *f.Request, params articleParams) ([]article, *f.Response) {
+	return []article{}, nil
+}
+
+func (articleResource) Create(req *f.Request, body *article) (*article, *f.Response) {
+	return body, nil
+}
+
+func (articleResource) Update(req *f.Request, params articleParams, body *article) (*article, *f.Response) {
+	return body, nil
+}
+
+func (articleResource) Delete(req *f.Request, params articleParams) *f.Response {
+	return nil
+}
+
+func TestListParams(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[articleParams]{
+		Method: "GET",
+		Path:   "/articles",
+		Handler: func(request *f.Request, params articleParams) *f.Response {
+			sort := []string{}
+			for _, s := range params.Sort.Get(f.SortField[articleSortField]{Field: "created", Desc: true}) {
+				if s.Desc {
+					sort = append(sort, "-"+string(s.Field))
+				} else {
+					sort = append(sort, string(s.Field))
+				}
+			}
+
+			return f.Respond.Ok().JSON(map[string]any{
+				"page":    params.Page.Page(),
+				"perPage": params.Page.PerPage(),
+				"offset":  params.Page.Offset(),
+				"sort":    sort,
+				"filters": params.Filter.Conditions(),
+			})
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/articles", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"page":1,"perPage":10,"offset":0,"sort":["-created"],"filters":null}`, string(body))
+
+	query := url.Values{}
+	query.Set("page", "3")
+	query.Set("per_page", "5")
+	query.Set("sort", "title,-created")
+	query.Set("filter[status][in]", "draft,published")
+	query.Set("filter[likes][gt]", "10")
+	query.Set("filter[author.name]", "ann")
+
+	body, resp = request("GET", baseUrl+"/articles?"+query.Encode(), nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{
+		"page": 3,
+		"perPage": 5,
+		"offset": 10,
+		"sort": ["title", "-created"],
+		"filters": [
+			{"Field": "author.name", "Operator": "eq", "Values": ["ann"]},
+			{"Field": "likes", "Operator": "gt", "Values": [10]},
+			{"Field": "status", "Operator": "in", "Values": ["draft", "published"]}
+		]
+	}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/articles?per_page=100&sort=likes", nil)
+	assert.Equal(400, resp.StatusCode)
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "one or more params are invalid",
+		"errors": [
+			{"param": "per_page", "detail": "invalid value of the `+"`per_page`"+` param: expected at most 50, got 100"},
+			{"param": "sort", "detail": "invalid value of the `+"`sort`"+` param: cannot sort by \"likes\", expected one of: created, title"}
+		]
+	}`, string(body))
+
+	_, resp = request("GET", baseUrl+"/articles?"+url.Values{"filter[status][gt]": {"a"}}.Encode(), nil)
+	assert.Equal(400, resp.StatusCode)
+
+	_, resp = request("GET", baseUrl+"/articles?"+url.Values{"filter[editor]": {"bob"}}.Encode(), nil)
+	assert.Equal(400, resp.StatusCode)
+
+	_, resp = request("GET", baseUrl+"/articles?"+url.Values{"filter[likes][lt]": {"many"}}.Encode(), nil)
+	assert.Equal(400, resp.StatusCode)
+}
+
+func TestRestEndpointsListParams(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Add(&f.RestEndpoints[articleParams, article]{
+		Path:     "/articles",
+		Resource: articleResource{},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	// list params are ignored by the endpoints operating on a single resource
+	body, resp := request("GET", baseUrl+"/articles/7?sort=likes", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"ID":"7","Title":"Article"}`, string(body))
+
+	_, resp = request("GET", baseUrl+"/articles?sort=likes", nil)
+	assert.Equal(400, resp.StatusCode)
+}
+
+func TestListParamsDocumentation(t *testing.T) {
+	assert := assert.New(t)
+
+	params := swag.NewParamsTypeStructure(articleParams{})
+
+	names := []string{}
+	for _, p := range params.ParamsIn("query") {
+		names = append(names, p.Name+" "+p.Rules)
+	}
+	assert.Equal([]string{
+		"page min=1",
+		"per_page min=1,max=50",
+		"sort ",
+		"filter[status] operators: eq, in",
+		"filter[likes] operators: gt, lt",
+		"filter[title] operators: contains",
+		"filter[author.name] operators: eq",
+	}, names)
+
+	assert.Empty(params.WithoutListParams().ParamsIn("query"))
+}
+
+type filterNode struct {
+	Name   *f.StringQParam
+	Parent *filterNode `filter:"nested"`
+}
+
+type untaggedFilter struct {
+	Filter *f.Filter[struct {
+		Author struct {
+			Name *f.StringQParam
+		}
+	}]
+}
+
+type recursiveFilter struct {
+	Filter *f.Filter[filterNode]
+}
+
+func TestInvalidListParams(t *testing.T) {
+	assert := assert.New(t)
+
+	type badPagination struct {
+		Page *f.Pagination `pagination:"per_page=200,max=100"`
+	}
+	type badFilter struct {
+		Filter *f.Filter[struct {
+			Status *f.StringQParam `filter:"like"`
+		}]
+	}
+
+	assert.PanicsWithValue("invalid pagination tag: per_page cannot be greater than max", func() {
+		f.CreateSearchParamsBinder[badPagination]()
+	})
+	assert.PanicsWithValue(`invalid filter tag on field Status: unknown operator "like"`, func() {
+		f.CreateSearchParamsBinder[badFilter]()
+	})
+	assert.PanicsWithValue("invalid filter field Author: must be a param type, or a struct with the `filter:\"nested\"` tag", func() {
+		f.CreateSearchParamsBinder[untaggedFilter]()
+	})
+	assert.PanicsWithValue("filter type butler_test.filterNode is recursive", func() {
+		f.CreateSearchParamsBinder[recursiveFilter]()
+	})
+}
@@ -0,0 +1,203 @@
+package butler
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/color"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// same banner echo prints when it starts the server by itself
+const echoBanner = `
+   ____    __
+  / __/___/ /  ___
+ / _// __/ _ \/ _ \
+/___/\__/_//_/\___/ %s
+High performance, minimalist Go web framework
+%s
+____________________________________O/_______
+                                    O\
+`
+
+// Returns the addresses of all the listeners the server is currently serving on.
+//
+// Useful when the server is listening on port 0, to read back the port that was chosen by the OS.
+func (server *Server) Addrs() []net.Addr {
+	server.listenersMx.Lock()
+	defer server.listenersMx.Unlock()
+
+	addrs := make([]net.Addr, 0, len(server.listeners))
+	for _, l := range server.listeners {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
+
+func (server *Server) openListeners() ([]net.Listener, error) {
+	addresses := server.Addresses
+	if len(addresses) == 0 {
+		addresses = []string{net.JoinHostPort(server.Host, fmt.Sprintf("%v", server.Port))}
+	}
+
+	listeners := make([]net.Listener, 0, len(addresses))
+	for _, address := range addresses {
+		var l net.Listener
+		var err error
+
+		if socketPath, isUnix := strings.CutPrefix(address, "unix:"); isUnix {
+			l, err = server.openUnixListener(socketPath)
+		} else {
+			l, err = net.Listen("tcp", address)
+		}
+
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func (server *Server) openUnixListener(socketPath string) (net.Listener, error) {
+	err := removeStaleSocket(socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := server.UnixSocketMode
+	if mode == 0 {
+		mode = 0660
+	}
+
+	err = os.Chmod(socketPath, mode)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// a socket file can be left behind if the previous process did not exit cleanly,
+// those have to be removed before a new socket can be bound to the same path
+func removeStaleSocket(socketPath string) error {
+	stat, err := os.Stat(socketPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if stat.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("file '%s' already exists and is not a unix socket", socketPath)
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket '%s' is already in use", socketPath)
+	}
+
+	return os.Remove(socketPath)
+}
+
+// Serves the first listener with the servers of echo, so that they are stopped by e.Shutdown() and e.Close().
+//
+// The listener is not assigned to e.Listener / e.TLSListener, which can only be done safely under the startup
+// lock of echo, use Server.Addrs() to read the addresses instead of e.ListenerAddr().
+func (server *Server) servePrimary(l net.Listener, tlsConfig *tls.Config) error {
+	e := server.echo
+
+	s := e.Server
+	var handler http.Handler = e
+	scheme := "http"
+	if tlsConfig != nil {
+		s = e.TLSServer
+		s.TLSConfig = tlsConfig
+		scheme = "https"
+	} else if server.H2C {
+		handler = h2c.NewHandler(e, &http2.Server{})
+	}
+	s.Handler = handler
+	s.ErrorLog = e.StdLogger
+
+	colorer := color.New()
+	colorer.SetOutput(e.Logger.Output())
+	if !e.HideBanner {
+		colorer.Printf(echoBanner, colorer.Red("v"+echo.Version), colorer.Blue("https://echo.labstack.com"))
+	}
+	if !e.HidePort {
+		colorer.Printf("⇨ %s server started on %s\n", scheme, colorer.Green(l.Addr()))
+	}
+	return s.Serve(l)
+}
+
+func (server *Server) createExtraServer(tlsConfig *tls.Config) *http.Server {
+	var handler http.Handler = server.echo
+	if tlsConfig == nil && server.H2C {
+		handler = h2c.NewHandler(server.echo, &http2.Server{})
+	}
+
+	httpServer := &http.Server{
+		Handler:   handler,
+		ErrorLog:  server.echo.StdLogger,
+		TLSConfig: tlsConfig,
+	}
+
+	server.listenersMx.Lock()
+	server.extraServers = append(server.extraServers, httpServer)
+	server.listenersMx.Unlock()
+
+	return httpServer
+}
+
+// Returns the port of the first TCP listener, which is where the clients are redirected to from the HTTP port
+func httpsPortOf(listeners []net.Listener, defaultPort int) int {
+	for _, l := range listeners {
+		if addr, ok := l.Addr().(*net.TCPAddr); ok {
+			return addr.Port
+		}
+	}
+	return defaultPort
+}
+
+func (server *Server) startRedirectServer(httpsPort int) {
+	redirect := server.TLS.createRedirectServer(httpsPort)
+
+	server.listenersMx.Lock()
+	server.extraServers = append(server.extraServers, redirect)
+	server.listenersMx.Unlock()
+
+	go func() {
+		err := redirect.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			server.echo.Logger.Error("https redirect server failed: ", err)
+		}
+	}()
+}
+
+func (server *Server) getExtraServers() []*http.Server {
+	server.listenersMx.Lock()
+	defer server.listenersMx.Unlock()
+	return append([]*http.Server{}, server.extraServers...)
+}
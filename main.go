@@ -2,9 +2,18 @@ package butler
 
 import (
 	"cmp"
-	"fmt"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"slices"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/sessions"
@@ -20,6 +29,10 @@ type EndpointParent interface {
 	GetMiddlewares() []Middleware
 	GetPath() string
 	GetAuthHandlers() []AuthHandler
+	GetTimeout() time.Duration
+	GetRequirements() []Requirement
+	GetConcurrencyLimits() []*ConcurrencyLimit
+	GetMaxBodySize() int64
 }
 
 type EndpointInterface interface {
@@ -33,15 +46,63 @@ type EndpointInterface interface {
 	GetParamsT() any
 	GetBodyT() any
 	GetResponseT() any
+	GetRequirements() []Requirement
 }
 
 type Server struct {
-	Cors         *CorsSettings
-	Port         int
-	echo         *echo.Echo
-	endpoints    []EndpointInterface
-	middlewares  []Middleware
-	usageMonitor UsageMonitor
+	Cors *CorsSettings
+	// Port the server will listen on. Ignored if `Addresses` are specified.
+	//
+	// Default: 80
+	Port int
+	// Optional. Host name or IP address the server should bind to. Ignored if `Addresses` are specified.
+	//
+	// Default: all interfaces
+	Host string
+	// Optional. List of addresses the server should listen on, requests from all of them are handled
+	// the same way. TCP addresses should be in a `host:port` format (e.g. `127.0.0.1:9090` or `:8080`),
+	// Unix domain sockets should be prefixed with `unix:` (e.g. `unix:/run/app.sock`).
+	Addresses []string
+	// File mode applied to the Unix domain sockets created by the server.
+	//
+	// Default: 0660
+	UnixSocketMode os.FileMode
+	// When set the server will only accept HTTPS connections
+	TLS *TLSSettings
+	// Allow HTTP/2 over cleartext TCP connections (h2c). Ignored when TLS is used.
+	H2C bool
+	// Maximum time the server will wait for in-flight requests to finish when
+	// shutting down after receiving a SIGINT or SIGTERM (see ListenAndHandleSignals)
+	//
+	// Default: 30 seconds
+	ShutdownTimeout time.Duration
+	// Optional. Server-wide limit of requests handled at once, that adjusts itself based on the observed latency.
+	AdaptiveConcurrency *AdaptiveConcurrencyLimit
+	// Optional. Maximum size of the request body in bytes, applied to every endpoint that does not specify its
+	// own limit. Requests with larger bodies receive a 413 response.
+	//
+	// The limit is read when an endpoint is added, it must be set before calling Add().
+	MaxBodySize int64
+	// Optional. Formats the error responses generated by the framework and by Respond.Problem(), by default
+	// the errors are sent as RFC 9457 `application/problem+json` objects.
+	ErrorFormatter ErrorFormatter
+	// Optional. Decoders of the request bodies keyed by the media type (e.g. `application/json`), used by all
+	// the endpoints. Endpoints can add or override decoders with their own `BodyDecoders`.
+	//
+	// The decoders are copied when an endpoint is added, they must be set before calling Add(), decoders added
+	// to the map afterwards are not used by the endpoints added earlier.
+	//
+	// Default: DefaultBodyDecoders()
+	BodyDecoders  map[string]BodyDecoder
+	echo          *echo.Echo
+	endpoints     []EndpointInterface
+	middlewares   []Middleware
+	usageMonitor  UsageMonitor
+	shutdownHooks []func()
+	shutdownOnce  sync.Once
+	listeners     []net.Listener
+	extraServers  []*http.Server
+	listenersMx   sync.Mutex
 }
 
 func CreateServer() *Server {
@@ -49,12 +110,16 @@ func CreateServer() *Server {
 
 	e.Logger = NewButlerLogger("", os.Stdout)
 
-	return &Server{
-		Port:      80,
-		Cors:      &CorsSettings{},
-		echo:      e,
-		endpoints: []EndpointInterface{},
+	server := &Server{
+		Port:            80,
+		ShutdownTimeout: 30 * time.Second,
+		Cors:            &CorsSettings{},
+		echo:            e,
+		endpoints:       []EndpointInterface{},
 	}
+	e.HTTPErrorHandler = server.handleEchoError
+
+	return server
 }
 
 func (server *Server) GetEcho() *echo.Echo {
@@ -72,6 +137,10 @@ func (server *Server) Logger() echo.Logger {
 func (server *Server) SetSessionStore(store sessions.Store) {
 	md := session.Middleware(store)
 	server.echo.Use(md)
+
+	if closer, ok := store.(io.Closer); ok {
+		server.OnShutdown(func() { closer.Close() })
+	}
 }
 
 func (server *Server) GetMiddlewares() []Middleware {
@@ -86,6 +155,22 @@ func (server *Server) GetAuthHandlers() []AuthHandler {
 	return []AuthHandler{}
 }
 
+func (server *Server) GetTimeout() time.Duration {
+	return 0
+}
+
+func (server *Server) GetRequirements() []Requirement {
+	return nil
+}
+
+func (server *Server) GetConcurrencyLimits() []*ConcurrencyLimit {
+	return nil
+}
+
+func (server *Server) GetMaxBodySize() int64 {
+	return server.MaxBodySize
+}
+
 func (server *Server) GetServer() *Server {
 	return server
 }
@@ -106,18 +191,172 @@ func (server *Server) Monitor(usageMonitor UsageMonitor) {
 	server.usageMonitor = usageMonitor
 }
 
+// Starts the server and blocks until it is closed.
+//
+// The server will listen on all the `Addresses`, or if none were specified, on `Host:Port`.
 func (server *Server) Listen() error {
+	listeners, err := server.openListeners()
+	if err != nil {
+		server.echo.Logger.Error(err)
+		return err
+	}
+
+	return server.ListenOn(listeners...)
+}
+
+// Starts serving on the given, already opened, listeners and blocks until the server is closed.
+//
+// This can be used when the listeners are created by an external process (e.g. systemd socket activation)
+// or when more control is required over how the listeners are opened.
+func (server *Server) ListenOn(listeners ...net.Listener) error {
+	if len(listeners) == 0 {
+		return errors.New("no listeners were provided")
+	}
+
 	server.echo.Use(cors.CORSWithConfig(server.Cors.config))
 
-	err := server.echo.Start(fmt.Sprintf(":%v", server.Port))
+	var tlsConfig *tls.Config
+	if server.TLS != nil {
+		cfg, err := server.TLS.buildConfig(server.echo.Logger)
+		if err != nil {
+			server.echo.Logger.Error(err)
+			for _, l := range listeners {
+				l.Close()
+			}
+			return err
+		}
+
+		tlsConfig = cfg
+		for idx := range listeners {
+			listeners[idx] = tls.NewListener(listeners[idx], cfg)
+		}
+
+		if server.TLS.RedirectFromPort != 0 {
+			server.startRedirectServer(httpsPortOf(listeners, server.Port))
+		}
+	}
+
+	server.listenersMx.Lock()
+	server.listeners = append(server.listeners, listeners...)
+	server.listenersMx.Unlock()
+
+	errs := make(chan error, len(listeners))
+
+	for _, l := range listeners[1:] {
+		httpServer := server.createExtraServer(tlsConfig)
+		go func() {
+			errs <- httpServer.Serve(l)
+		}()
+	}
+
+	go func() {
+		errs <- server.servePrimary(listeners[0], tlsConfig)
+	}()
+
+	var err error
+	for range listeners {
+		serveErr := <-errs
+		if err == nil && serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			// when one of the listeners fails, the server is brought down entirely
+			err = serveErr
+			server.echo.Logger.Error(err)
+			go server.Close()
+		}
+	}
+
+	if err == nil {
+		err = http.ErrServerClosed
+	}
+	return err
+}
+
+// Same as Listen, but additionally waits for a SIGINT or SIGTERM signal and once received
+// gracefully shuts down the server, waiting at most `ShutdownTimeout` for the in-flight requests
+// to finish.
+//
+// Returns once the server has been shut down.
+func (server *Server) ListenAndHandleSignals() error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- server.Listen()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-listenErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-signals:
+		server.echo.Logger.Infof("received %s, shutting down", sig)
+	}
+
+	timeout := server.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return server.Shutdown(ctx)
+}
+
+// Registers a function that will be called after the server has been shut down,
+// either by Shutdown() or Close(). Hooks run in the order they were registered.
+func (server *Server) OnShutdown(hook func()) {
+	server.shutdownHooks = append(server.shutdownHooks, hook)
+}
+
+// Gracefully shuts down the server. Stops accepting new connections and waits for all the
+// in-flight requests (including streamed and proxied responses) to finish.
+//
+// If the given context expires before all the requests finish, remaining connections
+// are closed forcefully and the context error is returned.
+//
+// OnShutdown hooks are run once all the connections are closed.
+func (server *Server) Shutdown(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, httpServer := range server.getExtraServers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			httpServer.Shutdown(ctx)
+		}()
+	}
+
+	err := server.echo.Shutdown(ctx)
+	wg.Wait()
+
 	if err != nil {
-		server.echo.Logger.Error(err)
+		server.echo.Logger.Error("graceful shutdown did not complete: ", err)
+		server.Close()
+		return err
 	}
+
+	server.runShutdownHooks()
 	return err
 }
 
+// Immediately closes the server and all of it's active connections.
 func (server *Server) Close() {
+	for _, httpServer := range server.getExtraServers() {
+		httpServer.Close()
+	}
 	server.echo.Close()
+	server.runShutdownHooks()
+}
+
+func (server *Server) runShutdownHooks() {
+	server.shutdownOnce.Do(func() {
+		for _, hook := range server.shutdownHooks {
+			hook()
+		}
+	})
 }
 
 func sortEndpoints(a, b swag.EndpointData) int {
@@ -147,17 +386,30 @@ func mapEndpoints(engpoints []EndpointInterface) []swag.EndpointData {
 	for _, endpoint := range engpoints {
 		sub := endpoint.GetSubRoutes()
 		uid, _ := uuid.NewV4()
+
+		paramsT := swag.NewParamsTypeStructure(endpoint.GetParamsT())
+		if e, ok := endpoint.(interface{ skipsListParams() bool }); ok && e.skipsListParams() {
+			paramsT = paramsT.WithoutListParams()
+		}
+
+		var accept []string
+		if e, ok := endpoint.(interface{ GetAcceptContentTypes() []string }); ok {
+			accept = e.GetAcceptContentTypes()
+		}
+
 		endpData = append(endpData, swag.EndpointData{
-			Uid:         uid.String(),
-			Name:        endpoint.GetName(),
-			Description: endpoint.GetDescription(),
-			Path:        endpoint.GetPath(),
-			Method:      endpoint.GetMethod(),
-			ParamsT:     swag.NewParamsTypeStructure(endpoint.GetParamsT()),
-			BodyT:       swag.NewTypeStructure(endpoint.GetBodyT()),
-			ResponseT:   swag.NewTypeStructure(endpoint.GetResponseT()),
-			IsGroup:     len(sub) > 0,
-			Children:    mapEndpoints(sub),
+			Uid:                uid.String(),
+			Name:               endpoint.GetName(),
+			Description:        endpoint.GetDescription(),
+			Path:               endpoint.GetPath(),
+			Method:             endpoint.GetMethod(),
+			ParamsT:            paramsT,
+			BodyT:              swag.NewTypeStructure(endpoint.GetBodyT()),
+			ResponseT:          swag.NewTypeStructure(endpoint.GetResponseT()),
+			AcceptContentTypes: accept,
+			Requires:           describeRequirements(endpoint.GetRequirements(), " and "),
+			IsGroup:            len(sub) > 0,
+			Children:           mapEndpoints(sub),
 		})
 	}
 
@@ -11,10 +11,19 @@ type MiddlewareResponseHandler func(
 	next func(response *Response),
 ) error
 
+// Wraps the execution of the endpoint handler, `next()` runs the subsequent around middlewares and the handler
+// and returns the response they produced. The response returned from this function is the one that will
+// be passed to the OnResponse middlewares.
+type MiddlewareAroundHandler func(
+	request *Request,
+	next func() *Response,
+) *Response
+
 type Middleware struct {
 	Name       string
 	OnRequest  MiddlewareRequestHandler
 	OnResponse MiddlewareResponseHandler
+	Around     MiddlewareAroundHandler
 }
 
 func getReqMiddlewares(middlewares []Middleware) []Middleware {
@@ -40,3 +49,33 @@ func getRespMiddlewares(middlewares []Middleware) []Middleware {
 
 	return handlers
 }
+
+func getAroundMiddlewares(middlewares []Middleware) []Middleware {
+	handlers := make([]Middleware, 0, len(middlewares))
+
+	for _, md := range middlewares {
+		if md.Around != nil {
+			handlers = append(handlers, md)
+		}
+	}
+
+	return handlers
+}
+
+// wraps the given handler with the around middlewares, first middleware on the list becomes the outermost one
+func composeAroundMiddlewares(middlewares []Middleware, request *Request, handler func() *Response) func() *Response {
+	next := handler
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		md := middlewares[i]
+		inner := next
+
+		next = func() *Response {
+			request.monitorStart(MonitorStep.AroundMiddleware, md.Name)
+			defer request.monitorEnd(MonitorStep.AroundMiddleware, md.Name)
+			return md.Around(request, inner)
+		}
+	}
+
+	return next
+}
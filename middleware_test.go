@@ -0,0 +1,178 @@
+package butler_test
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+// Records the order of calls made from the server goroutines
+type callOrder struct {
+	mx    sync.Mutex
+	calls []string
+}
+
+func (o *callOrder) add(name string) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	o.calls = append(o.calls, name)
+}
+
+func (o *callOrder) list() []string {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	return append([]string{}, o.calls...)
+}
+
+func orderMiddleware(name string, order *callOrder) f.Middleware {
+	return f.Middleware{
+		Name: name,
+		OnRequest: func(request *f.Request, respond func(response *f.Response)) error {
+			order.add(name)
+			return nil
+		},
+	}
+}
+
+func TestEndpointMiddlewares(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	order := &callOrder{}
+
+	server.Use(orderMiddleware("server", order))
+
+	group := &f.Group{
+		Path:        "/api",
+		Middlewares: []f.Middleware{orderMiddleware("group", order)},
+	}
+
+	basic := &f.BasicEndpoint[f.NoParams]{
+		Method:      "GET",
+		Path:        "/basic",
+		Middlewares: []f.Middleware{orderMiddleware("basic-field", order)},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text(strings.Join(order.list(), ","))
+		},
+	}
+	basic.Use(orderMiddleware("basic-use", order))
+
+	withBody := &f.Endpoint[f.NoParams, Book]{
+		Method: "POST",
+		Path:   "/body",
+		Handler: func(request *f.Request, params f.NoParams, body *Book) *f.Response {
+			return f.Respond.Ok().Text(strings.Join(order.list(), ","))
+		},
+	}
+	withBody.Use(f.Middleware{
+		Name: "short-circuit",
+		OnRequest: func(request *f.Request, respond func(response *f.Response)) error {
+			respond(f.Respond.Forbidden())
+			return nil
+		},
+	})
+
+	group.Add(basic)
+	group.Add(withBody)
+	server.Add(group)
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/api/basic", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("server,group,basic-field,basic-use", string(body))
+
+	_, resp = request("POST", baseUrl+"/api/body", Book{Title: "It"})
+	assert.Equal(403, resp.StatusCode)
+}
+
+func TestAroundMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	monitor := &recordingMonitor{}
+	server.Monitor(monitor)
+
+	order := &callOrder{}
+	var attempts atomic.Int32
+
+	server.Use(f.Middleware{
+		Name: "outer",
+		Around: func(request *f.Request, next func() *f.Response) *f.Response {
+			order.add("outer:before")
+			resp := next()
+			order.add("outer:after")
+			resp.Headers.Set("X-Wrapped", "true")
+			return resp
+		},
+	})
+
+	endp := &f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/flaky",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			order.add("handler")
+			if attempts.Add(1) < 2 {
+				return f.Respond.ServiceUnavailable()
+			}
+			return f.Respond.Ok().Text("ok")
+		},
+	}
+	endp.Use(f.Middleware{
+		Name: "retry",
+		Around: func(request *f.Request, next func() *f.Response) *f.Response {
+			resp := next()
+			if resp.Status == 503 {
+				resp = next()
+			}
+			return resp
+		},
+	})
+
+	panicking := &f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/panic",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			panic("handler panicked")
+		},
+	}
+
+	server.Add(endp)
+	server.Add(panicking)
+
+	server.Logger().SetOutput(&MockStdoutWriter{})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/flaky", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("ok", string(body))
+	assert.Equal("true", resp.Header.Get("X-Wrapped"))
+	assert.Equal([]string{"outer:before", "handler", "handler", "outer:after"}, order.list())
+
+	waitUntil(func() bool {
+		return len(monitor.Records()) == 1
+	})
+
+	steps := monitor.Records()[0].Steps
+	assert.Equal("middleware:around", steps[0].Step)
+	assert.Equal("outer", steps[0].Name)
+	assert.Equal("middleware:around", steps[1].Step)
+	assert.Equal("retry", steps[1].Name)
+	assert.Equal("handler", steps[2].Step)
+	assert.Equal("handler", steps[3].Step)
+	for _, step := range steps {
+		assert.NotNil(step.End)
+	}
+
+	_, resp = request("GET", baseUrl+"/panic", nil)
+	assert.Equal(500, resp.StatusCode)
+}
@@ -0,0 +1,166 @@
+package butler
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// Problem describes an error in the RFC 9457 format. All the errors generated by the framework are sent
+// as problems, by default serialized as `application/problem+json`. The format can be customized with
+// the server ErrorFormatter.
+type Problem struct {
+	// URI identifying the problem type
+	//
+	// Default: `about:blank`
+	Type string
+	// Short summary of the problem type
+	//
+	// Default: status text of the Status code
+	Title  string
+	Status int
+	// Explanation specific to this occurrence of the problem
+	Detail string
+	// URI identifying this occurrence of the problem
+	Instance string
+	// Additional members included in the problem object
+	Extensions map[string]any
+}
+
+// Creates a problem with the given status, the type and title are set to the defaults
+func NewProblem(status int, detail ...string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: firstOr(detail, ""),
+	}
+}
+
+// Adds an extension member to the problem
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	obj := make(map[string]any, len(p.Extensions)+5)
+	maps.Copy(obj, p.Extensions)
+
+	obj["type"] = p.Type
+	if obj["type"] == "" {
+		obj["type"] = "about:blank"
+	}
+	obj["status"] = p.Status
+	if p.Title != "" {
+		obj["title"] = p.Title
+	}
+	if p.Detail != "" {
+		obj["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		obj["instance"] = p.Instance
+	}
+
+	return json.Marshal(obj)
+}
+
+// Formats the problems into the responses body. The body and the headers of the returned response are used,
+// its status replaces the status of the original response unless it is 0.
+type ErrorFormatter func(request *Request, problem *Problem) *Response
+
+// Sends the problem as `application/problem+json`, keeping the status of the original response
+func ProblemJSONFormatter(request *Request, problem *Problem) *Response {
+	response := (&Response{}).JSON(problem)
+	response.Headers.Set("Content-Type", "application/problem+json")
+	return response
+}
+
+// HTTP error response with a RFC 9457 problem details body, the body is generated with
+// the server ErrorFormatter right before the response is sent
+func (resp) Problem(status int, detail ...string) *Response {
+	return Respond.FromProblem(NewProblem(status, detail...))
+}
+
+// Same as Respond.Problem() but uses the given problem details
+func (resp) FromProblem(problem *Problem) *Response {
+	return &Response{
+		Status:  problem.Status,
+		problem: problem,
+	}
+}
+
+// Problem details of the response, nil if the response was not created with Respond.Problem()
+func (resp *Response) Problem() *Problem {
+	return resp.problem
+}
+
+// renders the problem details into the body, unless a body was already assigned
+func (resp *Response) renderProblem(request *Request) {
+	if resp.problem == nil || resp.Body != nil || resp.streamReader != nil || resp.streamWriter != nil {
+		return
+	}
+
+	formatter := ProblemJSONFormatter
+	if request.server != nil && request.server.ErrorFormatter != nil {
+		formatter = request.server.ErrorFormatter
+	}
+
+	if resp.problem.Status == 0 {
+		resp.problem.Status = resp.Status
+	}
+
+	formatted := formatter(request, resp.problem)
+	if formatted == nil {
+		return
+	}
+
+	if formatted.Status != 0 {
+		resp.Status = formatted.Status
+	}
+	resp.Body = formatted.Body
+	for _, h := range formatted.Headers.httpHeaders {
+		resp.Headers.Del(h.name)
+		for _, v := range h.values {
+			resp.Headers.Add(h.name, v)
+		}
+	}
+}
+
+// handles the errors that did not come from the endpoints, e.g. unknown routes
+func (server *Server) handleEchoError(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	problem := NewProblem(http.StatusInternalServerError)
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		problem = NewProblem(httpErr.Code)
+		if msg, ok := httpErr.Message.(string); ok && httpErr.Code < 500 && msg != http.StatusText(httpErr.Code) {
+			problem.Detail = msg
+		}
+	} else {
+		ctx.Logger().Error(err)
+	}
+
+	request := NewRequest(ctx, voidRecorder{})
+	request.server = server
+
+	if ctx.Request().Method == http.MethodHead {
+		ctx.NoContent(problem.Status)
+		return
+	}
+
+	sendErr := Respond.FromProblem(problem).send(request)
+	if sendErr != nil {
+		ctx.Logger().Error(sendErr)
+	}
+}
@@ -0,0 +1,94 @@
+package butler_test
+
+import (
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+type problemParams struct {
+	Page *f.NumberQParam
+}
+
+func TestProblemResponses(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[problemParams]{
+		Method: "GET",
+		Path:   "/books",
+		Handler: func(request *f.Request, params problemParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/books/:id",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			response := f.Respond.Problem(404, "book does not exist")
+			response.Problem().Type = "https://example.com/problems/not-found"
+			response.Problem().With("id", 7)
+			return response
+		},
+	})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/private",
+		Auth: func(request *f.Request) *f.Ath {
+			return f.Auth.Unauthorized().WithChallenge(`Bearer realm="api"`)
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/books?page=first", nil)
+	assert.Equal(400, resp.StatusCode)
+	assert.Equal("application/problem+json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "invalid value of the `+"`page`"+` param: parsing to number failed",
+		"param": "page"
+	}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/books/7", nil)
+	assert.Equal(404, resp.StatusCode)
+	assert.JSONEq(`{
+		"type": "https://example.com/problems/not-found",
+		"title": "Not Found",
+		"status": 404,
+		"detail": "book does not exist",
+		"id": 7
+	}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/unknown", nil)
+	assert.Equal(404, resp.StatusCode)
+	assert.Equal("application/problem+json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(`{"type":"about:blank","title":"Not Found","status":404}`, string(body))
+
+	// custom formatter, the headers of the original response are preserved
+	server.ErrorFormatter = func(request *f.Request, problem *f.Problem) *f.Response {
+		response := &f.Response{}
+		if problem.Status == 404 {
+			response.Status = 410
+		}
+		return response.JSON(map[string]any{"error": problem.Title, "code": problem.Status})
+	}
+
+	body, resp = request("GET", baseUrl+"/private", nil)
+	assert.Equal(401, resp.StatusCode)
+	assert.Equal(`Bearer realm="api"`, resp.Header.Get("WWW-Authenticate"))
+	assert.Equal("application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.JSONEq(`{"error":"Unauthorized","code":401}`, string(body))
+
+	// the status of the formatted response replaces the original one
+	body, resp = request("GET", baseUrl+"/books/7", nil)
+	assert.Equal(410, resp.StatusCode)
+	assert.JSONEq(`{"error":"Not Found","code":404}`, string(body))
+}
@@ -2,6 +2,7 @@ package butler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -75,13 +76,19 @@ func createProxyHandler(response *Response, url string, opts *ProxyRequestOption
 
 			return nil
 		})
+		headersSent := false
 		req.AddValidator(func(res *http.Response) error {
 			respWriter.WriteHeader(res.StatusCode)
+			headersSent = true
 			return nil
 		})
 		req.ToWriter(respWriter)
 
-		return req.Fetch(context.Background())
+		err := req.Fetch(ctx.Request().Context())
+		if errors.Is(err, context.DeadlineExceeded) && !headersSent {
+			ctx.NoContent(504)
+		}
+		return err
 	}
 }
 
@@ -0,0 +1,364 @@
+package butler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RateLimitOptions struct {
+	// One of: `token-bucket`, `sliding-window`
+	//
+	// Default: `token-bucket`
+	Algorithm string
+	// Number of requests allowed within the Window
+	Limit int
+	// Default: 1 minute
+	Window time.Duration
+	// Maximum number of requests that can be made at once, only used by the token bucket algorithm.
+	// Tokens are refilled at the rate of Limit per Window.
+	//
+	// Default: same as Limit
+	Burst int
+	// Function that determines the key by which the requests are counted. Requests for which the function
+	// returns an empty string are not limited.
+	//
+	// Default: RateLimitByIP()
+	Key RateLimitKeyFunc
+	// Store in which the counters are kept.
+	//
+	// Default: new in-memory store
+	Store RateLimitStore
+	// Prefix added to the keys, to separate the counters of different limiters using the same store
+	Name string
+}
+
+type RateLimitKeyFunc func(request *Request) string
+
+// State of a single rate limit counter
+type RateLimitState struct {
+	// token bucket
+	Tokens  float64
+	Updated time.Time
+	// sliding window
+	Current     int
+	Previous    int
+	WindowStart time.Time
+}
+
+// RateLimitStore keeps the rate limit counters. Implement this interface to share the counters
+// between multiple server instances (e.g. using Redis).
+type RateLimitStore interface {
+	// Atomically loads the state stored under the key (zero value if there is none), passes it to the update
+	// function and stores the result. The state can be removed once it was not updated for the ttl.
+	Update(ctx context.Context, key string, ttl time.Duration, update func(state RateLimitState) RateLimitState) error
+}
+
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// RateLimit creates a middleware that limits the number of requests a client can make. Requests over the
+// limit receive a 429 response with a Retry-After header. All responses include the `RateLimit-Limit`,
+// `RateLimit-Remaining` and `RateLimit-Reset` headers.
+func RateLimit(opts RateLimitOptions) Middleware {
+	if opts.Limit <= 0 {
+		panic("rate limit must be greater than 0")
+	}
+	if opts.Window == 0 {
+		opts.Window = time.Minute
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = "token-bucket"
+	}
+	if opts.Burst == 0 {
+		opts.Burst = opts.Limit
+	}
+	if opts.Key == nil {
+		opts.Key = RateLimitByIP()
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore()
+	}
+
+	var take func(state RateLimitState, now time.Time) (RateLimitState, rateLimitResult)
+	switch opts.Algorithm {
+	case "token-bucket":
+		take = opts.takeToken
+	case "sliding-window":
+		take = opts.takeSlidingWindow
+	default:
+		panic("invalid rate limit algorithm: " + opts.Algorithm)
+	}
+
+	// the state is kept until the bucket is refilled, or for the current and the previous window
+	limit := opts.Limit
+	ttl := 2 * opts.Window
+	if opts.Algorithm == "token-bucket" {
+		limit = opts.Burst
+		ttl = max(opts.Window, time.Duration(float64(opts.Burst)/float64(opts.Limit)*float64(opts.Window)))
+	}
+
+	return Middleware{
+		Name: "rate_limit",
+		OnRequest: func(request *Request, respond func(response *Response)) error {
+			key := opts.Key(request)
+			if key == "" {
+				return nil
+			}
+
+			var result rateLimitResult
+			now := time.Now()
+			err := opts.Store.Update(request.Context(), opts.Name+":"+key, ttl, func(state RateLimitState) RateLimitState {
+				state, result = take(state, now)
+				return state
+			})
+			if err != nil {
+				// the limiter should not take the whole service down when the store is unavailable
+				request.Logger.Error("rate limit store failed: ", err)
+				return nil
+			}
+
+			headers := request.EchoContext().Response().Header()
+			headers.Set("RateLimit-Limit", fmt.Sprint(limit))
+			headers.Set("RateLimit-Remaining", fmt.Sprint(result.remaining))
+			headers.Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(result.reset)))
+
+			if !result.allowed {
+				response := Respond.Problem(429, "rate limit exceeded")
+				response.Headers.Set("Retry-After", fmt.Sprint(ceilSeconds(result.retryAfter)))
+				respond(response)
+			}
+
+			return nil
+		},
+	}
+}
+
+func (opts *RateLimitOptions) takeToken(state RateLimitState, now time.Time) (RateLimitState, rateLimitResult) {
+	capacity := float64(opts.Burst)
+	perSecond := float64(opts.Limit) / opts.Window.Seconds()
+
+	if state.Updated.IsZero() {
+		state.Tokens = capacity
+	} else {
+		elapsed := now.Sub(state.Updated).Seconds()
+		state.Tokens = math.Min(capacity, state.Tokens+elapsed*perSecond)
+	}
+	state.Updated = now
+
+	result := rateLimitResult{}
+	if state.Tokens >= 1 {
+		state.Tokens--
+		result.allowed = true
+	} else {
+		result.retryAfter = secondsToDuration((1 - state.Tokens) / perSecond)
+	}
+
+	result.remaining = int(math.Floor(state.Tokens))
+	result.reset = secondsToDuration((capacity - state.Tokens) / perSecond)
+	return state, result
+}
+
+func (opts *RateLimitOptions) takeSlidingWindow(state RateLimitState, now time.Time) (RateLimitState, rateLimitResult) {
+	windowStart := now.Truncate(opts.Window)
+	if !state.WindowStart.Equal(windowStart) {
+		if state.WindowStart.Add(opts.Window).Equal(windowStart) {
+			state.Previous = state.Current
+		} else {
+			state.Previous = 0
+		}
+		state.Current = 0
+		state.WindowStart = windowStart
+	}
+
+	elapsed := now.Sub(windowStart)
+	untilNextWindow := opts.Window - elapsed
+	// requests from the previous window are weighted by how much of it still overlaps with the sliding window
+	weight := 1 - elapsed.Seconds()/opts.Window.Seconds()
+	estimated := float64(state.Previous)*weight + float64(state.Current)
+
+	result := rateLimitResult{reset: untilNextWindow}
+	if estimated+1 <= float64(opts.Limit) {
+		state.Current++
+		estimated++
+		result.allowed = true
+	} else if state.Current+1 > opts.Limit {
+		result.retryAfter = untilNextWindow
+	} else {
+		// wait until enough of the previous window slides out
+		allowedPrevious := float64(opts.Limit - 1 - state.Current)
+		wait := opts.Window.Seconds()*(1-allowedPrevious/float64(state.Previous)) - elapsed.Seconds()
+		result.retryAfter = secondsToDuration(wait)
+	}
+
+	result.remaining = max(0, opts.Limit-int(math.Ceil(estimated)))
+	return state, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return max(0, int(math.Ceil(d.Seconds())))
+}
+
+// #region Keys
+
+// Counts the requests by the client IP. When the request comes from one of the trusted proxies
+// (IPs or CIDR ranges), the client IP is taken from the X-Forwarded-For or X-Real-IP headers.
+func RateLimitByIP(trustedProxies ...string) RateLimitKeyFunc {
+	trusted := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, p := range trustedProxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				panic("invalid trusted proxy address: " + p)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trusted = append(trusted, prefix)
+	}
+
+	isTrusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(request *Request) string {
+		return "ip:" + clientIP(request, isTrusted)
+	}
+}
+
+func clientIP(request *Request, isTrusted func(ip string) bool) string {
+	remote, _, err := net.SplitHostPort(request.HttpRequest().RemoteAddr)
+	if err != nil {
+		remote = request.HttpRequest().RemoteAddr
+	}
+	if isTrusted == nil || !isTrusted(remote) {
+		return remote
+	}
+
+	if forwarded := request.Headers.Get("X-Forwarded-For"); forwarded != "" {
+		ips := strings.Split(forwarded, ",")
+		// the rightmost address not added by one of the trusted proxies is the client
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if !isTrusted(ip) || i == 0 {
+				return ip
+			}
+		}
+	}
+
+	if realIP := request.Headers.Get("X-Real-IP"); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+
+	return remote
+}
+
+// Counts the requests by the ID of the principal provided by the auth handlers. Strings and integers are used
+// as the ID directly, other principals must implement PrincipalIdentity (like JWTClaims, which is identified
+// by the `iss` and `sub` claims) or fmt.Stringer.
+//
+// Requests without a principal, or with a principal that has no ID, are counted by the client IP.
+func RateLimitByPrincipal() RateLimitKeyFunc {
+	return func(request *Request) string {
+		principal := request.Principal()
+		if principal == nil {
+			return "ip:" + clientIP(request, nil)
+		}
+		id, ok := principalID(principal)
+		if !ok {
+			request.Logger.Warnf("principal of type %T has no ID, the request is rate limited by the client IP", principal)
+			return "ip:" + clientIP(request, nil)
+		}
+		return "principal:" + id
+	}
+}
+
+// Counts the requests by the api key from the given header, requests without a key
+// are counted by the client IP
+func RateLimitByAPIKey(header string) RateLimitKeyFunc {
+	return func(request *Request) string {
+		key := request.Headers.Get(header)
+		if key == "" {
+			return "ip:" + clientIP(request, nil)
+		}
+		return "key:" + APIKeyID(key)
+	}
+}
+
+// #endregion Keys
+
+// #region Memory Store
+
+type memoryRateLimitEntry struct {
+	state     RateLimitState
+	expiresAt time.Time
+}
+
+// In-memory RateLimitStore, expired counters are removed periodically while the store is used.
+type MemoryRateLimitStore struct {
+	mx        sync.Mutex
+	entries   map[string]*memoryRateLimitEntry
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries:   map[string]*memoryRateLimitEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Update(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	update func(state RateLimitState) RateLimitState,
+) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.lastSweep = now
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	entry, ok := s.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryRateLimitEntry{}
+		s.entries[key] = entry
+	}
+
+	entry.state = update(entry.state)
+	entry.expiresAt = now.Add(ttl)
+	return nil
+}
+
+// #endregion Memory Store
@@ -0,0 +1,197 @@
+package butler_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	limited := &f.Group{
+		Path: "/limited",
+		Middlewares: []f.Middleware{
+			f.RateLimit(f.RateLimitOptions{
+				Limit:  2,
+				Window: time.Minute,
+				Key:    f.RateLimitByIP("127.0.0.1"),
+			}),
+		},
+	}
+	limited.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/token-bucket",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+	server.Add(limited)
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/sliding-window",
+		Middlewares: []f.Middleware{
+			f.RateLimit(f.RateLimitOptions{
+				Algorithm: "sliding-window",
+				Limit:     3,
+				Window:    time.Hour,
+				Key:       f.RateLimitByAPIKey("X-API-Key"),
+			}),
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	client := header{"X-Forwarded-For", "203.0.113.7, 127.0.0.1"}
+
+	_, resp := request("GET", baseUrl+"/limited/token-bucket", nil, client)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("2", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal("1", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal("30", resp.Header.Get("RateLimit-Reset"))
+
+	_, resp = request("GET", baseUrl+"/limited/token-bucket", nil, client)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("0", resp.Header.Get("RateLimit-Remaining"))
+
+	_, resp = request("GET", baseUrl+"/limited/token-bucket", nil, client)
+	assert.Equal(429, resp.StatusCode)
+	assert.Equal("30", resp.Header.Get("Retry-After"))
+
+	// a different client behind the same trusted proxy has its own bucket
+	_, resp = request("GET", baseUrl+"/limited/token-bucket", nil, header{"X-Forwarded-For", "203.0.113.8"})
+	assert.Equal(200, resp.StatusCode)
+
+	for i := range 3 {
+		_, resp = request("GET", baseUrl+"/sliding-window", nil, header{"X-API-Key", "key-1"})
+		assert.Equal(200, resp.StatusCode)
+		assert.Equal(strconv.Itoa(2-i), resp.Header.Get("RateLimit-Remaining"))
+	}
+
+	_, resp = request("GET", baseUrl+"/sliding-window", nil, header{"X-API-Key", "key-1"})
+	assert.Equal(429, resp.StatusCode)
+	assert.NotEmpty(resp.Header.Get("Retry-After"))
+
+	_, resp = request("GET", baseUrl+"/sliding-window", nil, header{"X-API-Key", "key-2"})
+	assert.Equal(200, resp.StatusCode)
+}
+
+type rateLimitedUser struct {
+	ID    string
+	Token string
+}
+
+func TestRateLimitByPrincipal(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/limited",
+		Auth: func(request *f.Request) *f.Ath {
+			token := request.Headers.Get("X-Token")
+			if request.Headers.Get("X-Kind") == "struct" {
+				return f.Auth.Ok().WithPrincipal(rateLimitedUser{ID: "user-1", Token: token})
+			}
+			return f.Auth.Ok().WithPrincipal(f.JWTClaims{"sub": request.Headers.Get("X-Sub"), "jti": token})
+		},
+		Middlewares: []f.Middleware{
+			f.RateLimit(f.RateLimitOptions{
+				Limit:  1,
+				Window: time.Hour,
+				Key:    f.RateLimitByPrincipal(),
+			}),
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	_, resp := request("GET", baseUrl+"/limited", nil, header{"X-Sub", "user-1"}, header{"X-Token", "a"})
+	assert.Equal(200, resp.StatusCode)
+
+	// a newly issued token of the same user shares the limit
+	_, resp = request("GET", baseUrl+"/limited", nil, header{"X-Sub", "user-1"}, header{"X-Token", "b"})
+	assert.Equal(429, resp.StatusCode)
+
+	_, resp = request("GET", baseUrl+"/limited", nil, header{"X-Sub", "user-2"}, header{"X-Token", "c"})
+	assert.Equal(200, resp.StatusCode)
+
+	// principals without an ID are counted by the IP
+	_, resp = request("GET", baseUrl+"/limited", nil, header{"X-Kind", "struct"}, header{"X-Token", "d"})
+	assert.Equal(200, resp.StatusCode)
+	_, resp = request("GET", baseUrl+"/limited", nil, header{"X-Kind", "struct"}, header{"X-Token", "e"})
+	assert.Equal(429, resp.StatusCode)
+}
+
+type ttlRecordingStore struct {
+	mx   sync.Mutex
+	ttls []time.Duration
+}
+
+func (s *ttlRecordingStore) Update(ctx context.Context, key string, ttl time.Duration, update func(state f.RateLimitState) f.RateLimitState) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.ttls = append(s.ttls, ttl)
+	update(f.RateLimitState{})
+	return nil
+}
+
+func TestRateLimitStateTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	store := &ttlRecordingStore{}
+
+	server := f.CreateServer()
+	for path, opts := range map[string]f.RateLimitOptions{
+		"/bucket":      {Limit: 2, Window: time.Minute, Store: store},
+		"/large-burst": {Limit: 2, Burst: 10, Window: time.Minute, Store: store},
+		"/sliding":     {Algorithm: "sliding-window", Limit: 2, Window: time.Minute, Store: store},
+		"/small-burst": {Limit: 10, Burst: 1, Window: time.Minute, Store: store},
+	} {
+		server.Add(&f.BasicEndpoint[f.NoParams]{
+			Method:      "GET",
+			Path:        path,
+			Middlewares: []f.Middleware{f.RateLimit(opts)},
+			Handler: func(request *f.Request, params f.NoParams) *f.Response {
+				return f.Respond.Ok()
+			},
+		})
+	}
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	ttlOf := func(path string) time.Duration {
+		store.mx.Lock()
+		store.ttls = nil
+		store.mx.Unlock()
+
+		request("GET", baseUrl+path, nil)
+
+		store.mx.Lock()
+		defer store.mx.Unlock()
+		return store.ttls[0]
+	}
+
+	assert.Equal(time.Minute, ttlOf("/bucket"))
+	// an emptied bucket is kept until it is refilled
+	assert.Equal(5*time.Minute, ttlOf("/large-burst"))
+	assert.Equal(time.Minute, ttlOf("/small-burst"))
+	assert.Equal(2*time.Minute, ttlOf("/sliding"))
+}
@@ -1,9 +1,15 @@
 package butler
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"reflect"
+	"slices"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
@@ -14,13 +20,23 @@ type Request struct {
 	Method  string
 	Headers http.Header
 	Path    string
-	Data    map[string]any
-	Logger  RequestLogger
+	// Untyped values shared between the auth handlers, middlewares and the endpoint handler.
+	//
+	// Prefer the typed keys created with NewKey.
+	Data   map[string]any
+	Logger RequestLogger
 
 	monitor          monitorRecorder
 	monitorRecord    RecordBuilder
 	ctx              echo.Context
-	accessedSessions []*sessions.Session
+	accessedSessions []*accessedSession
+	values           map[any]any
+	principal        any
+	scopes           []string
+	roles            []string
+	bodyTooLarge     bool
+	server           *Server
+	afterResponse    []func()
 }
 
 func NewRequest(ctx echo.Context, monitor monitorRecorder) *Request {
@@ -52,11 +68,108 @@ func (r *Request) Session(sessionName ...string) (*sessions.Session, error) {
 		return nil, err
 	}
 
-	r.accessedSessions = append(r.accessedSessions, s)
+	r.trackSession(s)
 
 	return s, err
 }
 
+// Assigns a new ID to the session, the data stored under the previous ID is removed. Should be used on login
+// and on privilege changes, to prevent session fixation.
+//
+// Default session name: "session"
+func (r *Request) RegenerateSession(sessionName ...string) (*sessions.Session, error) {
+	s, err := r.Session(sessionName...)
+	if err != nil {
+		return nil, err
+	}
+
+	if store, ok := s.Store().(*SessionStore); ok {
+		err = store.Regenerate(r.Context(), s)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s.ID = ""
+	}
+
+	return s, nil
+}
+
+// Removes all the sessions of the given user ("log out everywhere"), requires the server session store
+// to be a butler.SessionStore and the sessions to be marked with butler.SetSessionUser()
+func (r *Request) RevokeUserSessions(userID string) error {
+	store, ok := r.ctx.Get("_session_store").(*SessionStore)
+	if !ok {
+		return errNoButlerSessionStore
+	}
+	return store.RevokeUser(r.Context(), userID)
+}
+
+// Adds a flash message to the default session, it will be available in the next request
+// that reads the flashes. Values of custom types must be registered with gob.Register().
+func (r *Request) AddFlash(value any, category ...string) error {
+	s, err := r.Session()
+	if err != nil {
+		return err
+	}
+	s.AddFlash(value, category...)
+	return nil
+}
+
+// Returns and removes the flash messages from the default session
+func (r *Request) Flashes(category ...string) ([]any, error) {
+	s, err := r.Session()
+	if err != nil {
+		return nil, err
+	}
+	return s.Flashes(category...), nil
+}
+
+// Returns the context of this request. The context gets cancelled when the client disconnects,
+// or when the endpoint Timeout is exceeded.
+func (r *Request) Context() context.Context {
+	return r.ctx.Request().Context()
+}
+
+// True if the client has closed the connection before the response was sent.
+func (r *Request) ClientDisconnected() bool {
+	return errors.Is(r.Context().Err(), context.Canceled)
+}
+
+// Returns the principal provided by the auth handlers, or nil if there was none.
+//
+// Use butler.GetPrincipal() to get the principal of a specific type.
+func (r *Request) Principal() any {
+	return r.principal
+}
+
+// Returns all the scopes granted by the auth handlers
+func (r *Request) Scopes() []string {
+	return r.scopes
+}
+
+// True if the given scope was granted to this request by the auth handlers
+func (r *Request) HasScope(scope string) bool {
+	return slices.Contains(r.scopes, scope)
+}
+
+// Returns all the roles granted by the auth handlers
+func (r *Request) Roles() []string {
+	return r.roles
+}
+
+// True if the given role was granted to this request by the auth handlers, or if the principal
+// implements the RoleHolder interface and has the given role
+func (r *Request) HasRole(role string) bool {
+	if slices.Contains(r.roles, role) {
+		return true
+	}
+	if holder, ok := r.principal.(RoleHolder); ok {
+		return holder.HasRole(role)
+	}
+	return false
+}
+
 func (r *Request) HttpRequest() *http.Request {
 	return r.ctx.Request()
 }
@@ -93,15 +206,95 @@ func (r *Request) monitorEnd(step, name string) {
 	r.monitorRecord.StepEnd(step, name)
 }
 
+func (r *Request) monitorEndWithResult(step, name, result string) {
+	r.monitorRecord.StepEndWithResult(step, name, result)
+}
+
+// Registers a function that runs once the response has been sent, or the request handling has failed.
+// Hooks run in the reverse order they were registered.
+func (r *Request) AfterResponse(hook func()) {
+	r.afterResponse = append(r.afterResponse, hook)
+}
+
+func (r *Request) runAfterResponseHooks() {
+	for _, hook := range slices.Backward(r.afterResponse) {
+		hook()
+	}
+}
+
 func (r *Request) completeMonitor() {
 	r.monitor.FinalizeRecord(r.monitorRecord)
 }
 
+func (r *Request) trackSession(s *sessions.Session) {
+	for _, accessed := range r.accessedSessions {
+		if accessed.session == s {
+			return
+		}
+	}
+	r.accessedSessions = append(r.accessedSessions, newAccessedSession(s))
+}
+
+// saves the sessions that were changed during the request handling
 func (r *Request) saveSessions() {
-	for _, s := range r.accessedSessions {
-		err := s.Save(r.ctx.Request(), r.ctx.Response())
+	for _, accessed := range r.accessedSessions {
+		if !accessed.changed() {
+			continue
+		}
+
+		err := accessed.session.Save(r.ctx.Request(), r.ctx.Response())
 		if err != nil {
 			r.Logger.Error("failed to save the session: ", err)
 		}
+		accessed.reset()
+	}
+}
+
+// snapshot of a session taken when it was first accessed, used to detect changes
+type accessedSession struct {
+	session *sessions.Session
+	id      string
+	options sessions.Options
+	// deep copy of the values, nil if the values could not be copied
+	values map[any]any
+}
+
+func newAccessedSession(s *sessions.Session) *accessedSession {
+	a := &accessedSession{session: s}
+	a.reset()
+	return a
+}
+
+func (a *accessedSession) reset() {
+	a.id = a.session.ID
+	if a.session.Options != nil {
+		a.options = *a.session.Options
+	}
+	a.values = copySessionValues(a.session.Values)
+}
+
+func (a *accessedSession) changed() bool {
+	s := a.session
+	if a.values == nil || s.ID != a.id {
+		return true
+	}
+	if s.Options != nil && *s.Options != a.options {
+		return true
+	}
+	return !reflect.DeepEqual(s.Values, a.values)
+}
+
+func copySessionValues(values map[any]any) map[any]any {
+	var buff bytes.Buffer
+	err := gob.NewEncoder(&buff).Encode(values)
+	if err != nil {
+		return nil
+	}
+
+	copied := map[any]any{}
+	err = gob.NewDecoder(&buff).Decode(&copied)
+	if err != nil {
+		return nil
 	}
+	return copied
 }
@@ -0,0 +1,166 @@
+package butler_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Logger().SetOutput(&MockStdoutWriter{})
+
+	waitForContext := func(request *f.Request, params f.NoParams) *f.Response {
+		select {
+		case <-request.Context().Done():
+			return f.Respond.InternalError()
+		case <-time.After(time.Second / 2):
+			return f.Respond.Ok().Text("finished")
+		}
+	}
+
+	group := &f.Group{
+		Path:    "/api",
+		Timeout: 50 * time.Millisecond,
+	}
+
+	group.Add(&f.BasicEndpoint[f.NoParams]{
+		Method:  "GET",
+		Path:    "/inherited",
+		Handler: waitForContext,
+	})
+	group.Add(&f.BasicEndpoint[f.NoParams]{
+		Method:  "GET",
+		Path:    "/disabled",
+		Timeout: -1,
+		Handler: waitForContext,
+	})
+	server.Add(group)
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method:  "GET",
+		Path:    "/own",
+		Timeout: 50 * time.Millisecond,
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			deadline, ok := request.Context().Deadline()
+			if !ok || time.Until(deadline) > 50*time.Millisecond {
+				return f.Respond.BadRequest()
+			}
+			return waitForContext(request, params)
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	_, resp := request("GET", baseUrl+"/api/inherited", nil)
+	assert.Equal(503, resp.StatusCode)
+
+	_, resp = request("GET", baseUrl+"/own", nil)
+	assert.Equal(503, resp.StatusCode)
+
+	body, resp := request("GET", baseUrl+"/api/disabled", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("finished", string(body))
+}
+
+func TestClientDisconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	disconnected := make(chan bool, 1)
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/wait",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			select {
+			case <-request.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			disconnected <- request.ClientDisconnected()
+			return f.Respond.Ok()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseUrl+"/wait", nil)
+	noErr(err)
+	_, err = http.DefaultClient.Do(req)
+	assert.NotNil(err)
+
+	assert.True(<-disconnected)
+}
+
+type TestUser struct {
+	ID   int
+	Name string
+}
+
+var requestIDKey = f.NewKey[string]("requestID")
+
+func TestTypedRequestValues(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+
+	otherKey := f.NewKey[string]("requestID")
+	errKey := f.NewKey[error]("error")
+
+	server.Use(f.Middleware{
+		Name: "request-id",
+		OnRequest: func(request *f.Request, respond func(response *f.Response)) error {
+			requestIDKey.Set(request, "req-1")
+			return nil
+		},
+	})
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/me",
+		Auth: func(request *f.Request) *f.Ath {
+			return f.Auth.Ok().WithPrincipal(TestUser{1, "John"})
+		},
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			id, ok := requestIDKey.Get(request)
+			if !ok {
+				return f.Respond.InternalError()
+			}
+			if otherKey.Has(request) {
+				return f.Respond.InternalError()
+			}
+
+			// a nil interface value is reported as not set
+			errKey.Set(request, nil)
+			if err, ok := errKey.Get(request); ok || err != nil {
+				return f.Respond.InternalError()
+			}
+
+			user, ok := f.GetPrincipal[TestUser](request)
+			if !ok {
+				return f.Respond.Unauthorized()
+			}
+
+			return f.Respond.Ok().Text(id + ":" + user.Name + ":" + otherKey.GetOr(request, "none"))
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/me", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("req-1:John:none", string(body))
+}
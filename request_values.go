@@ -0,0 +1,93 @@
+package butler
+
+import "fmt"
+
+// Key is used to store and retrieve typed values in the Request. Values set by auth handlers or middlewares
+// can be then accessed in the endpoint handlers without type assertions.
+//
+// Each key created with NewKey is unique, two keys with the same name will not overwrite each others values.
+//
+// @example
+//
+//	var UserKey = butler.NewKey[User]("user")
+//
+//	// in a middleware
+//	UserKey.Set(request, user)
+//
+//	// in the handler
+//	user, ok := UserKey.Get(request)
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name}
+}
+
+func (k *Key[T]) Name() string {
+	return k.name
+}
+
+// Stores the value in the request, replacing the previously set value
+func (k *Key[T]) Set(request *Request, value T) {
+	if request.values == nil {
+		request.values = map[any]any{}
+	}
+	request.values[k] = value
+}
+
+// Returns the value stored in the request, second return value is false if the value was never set, or if
+// T is an interface type and the stored value is nil
+func (k *Key[T]) Get(request *Request) (T, bool) {
+	v, ok := request.values[k].(T)
+	return v, ok
+}
+
+// Same as Get, but returns the default value if the value was never set
+func (k *Key[T]) GetOr(request *Request, defaultValue T) T {
+	v, ok := k.Get(request)
+	if !ok {
+		return defaultValue
+	}
+	return v
+}
+
+func (k *Key[T]) Has(request *Request) bool {
+	_, ok := request.values[k]
+	return ok
+}
+
+func (k *Key[T]) Delete(request *Request) {
+	delete(request.values, k)
+}
+
+// Returns the principal provided by the auth handlers (see Ath.WithPrincipal), second return value is
+// false if there is no principal or if it's not of the type T
+func GetPrincipal[T any](request *Request) (T, bool) {
+	p, ok := request.principal.(T)
+	return p, ok
+}
+
+// Implemented by the principals that can be identified by a stable ID, which stays the same across all the
+// requests of the same user or client (e.g. the user ID, not the whole session or token). Used by
+// RateLimitByPrincipal.
+type PrincipalIdentity interface {
+	PrincipalID() string
+}
+
+// Returns the stable ID of the principal, second return value is false if the principal has none. Strings
+// and integers are used as they are, other principals must implement PrincipalIdentity or fmt.Stringer.
+func principalID(principal any) (string, bool) {
+	var id string
+	switch p := principal.(type) {
+	case PrincipalIdentity:
+		id = p.PrincipalID()
+	case string:
+		id = p
+	case fmt.Stringer:
+		id = p.String()
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		id = fmt.Sprint(p)
+	}
+	return id, id != ""
+}
@@ -33,6 +33,7 @@ type Response struct {
 	logs              []responseLog
 	streamReader      ButlerReader
 	streamWriter      func(HttpWriter) error
+	problem           *Problem
 }
 
 // marks this response to be encoded with a given encoding (one of: `auto`, `none`, `gzip`, `brotli`, `deflate`)
@@ -334,6 +335,8 @@ func (resp *Response) send(request *Request) error {
 		return err
 	}
 
+	resp.renderProblem(request)
+
 	if resp.AllowStreaming {
 		resp.Headers.Set("Accept-Ranges", "bytes")
 	}
@@ -666,6 +669,13 @@ func (resp) MisdirectedRequest() *Response {
 	}
 }
 
+// HTTP Code: 422
+func (resp) UnprocessableEntity() *Response {
+	return &Response{
+		Status: 422,
+	}
+}
+
 // HTTP Code: 426
 func (resp) UpgradeRequired() *Response {
 	return &Response{
@@ -1,11 +1,19 @@
 package butler
 
 import (
+	"encoding"
 	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
 )
 
 type NoParams struct{}
@@ -47,6 +55,10 @@ func (p *StringQParam) Set(value string) *ParamParsingError {
 	return nil
 }
 
+func (p *StringQParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
 func (p *StringQParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	v := ctx.QueryParam(name)
 	if v != "" {
@@ -83,7 +95,7 @@ func (p *NumberQParam) Get(defaultValue ...int64) int64 {
 func (p *NumberQParam) Set(value string) *ParamParsingError {
 	num, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return &ParamParsingError{400, "Bad Request", "parsing to number failed", ""}
+		return &ParamParsingError{400, "Bad Request", "parsing to number failed", "", false}
 	}
 
 	p.value = num
@@ -91,6 +103,10 @@ func (p *NumberQParam) Set(value string) *ParamParsingError {
 	return nil
 }
 
+func (p *NumberQParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
 func (p *NumberQParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	v := ctx.QueryParam(name)
 	if v != "" {
@@ -130,6 +146,10 @@ func (p *BoolQParam) Set(value string) *ParamParsingError {
 	return nil
 }
 
+func (p *BoolQParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
 func (p *BoolQParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	v := ctx.QueryParam(name)
 	if v != "" {
@@ -138,6 +158,326 @@ func (p *BoolQParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	return nil
 }
 
+type FloatQParam struct {
+	value float64
+	isSet bool
+}
+
+func (p *FloatQParam) IsQueryParam() bool {
+	return true
+}
+
+func (p *FloatQParam) AcceptedKind() string {
+	return reflect.Float64.String()
+}
+
+// True if the request contained this param
+func (p *FloatQParam) Has() bool {
+	return p.isSet
+}
+
+func (p *FloatQParam) Get(defaultValue ...float64) float64 {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *FloatQParam) Set(value string) *ParamParsingError {
+	num, err := parseFloatParam(value)
+	if err != nil {
+		return err
+	}
+
+	p.value = num
+	p.isSet = true
+	return nil
+}
+
+func (p *FloatQParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *FloatQParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.QueryParam(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+// Accepts RFC 3339 date-times (e.g. `2024-05-01T10:00:00Z`) and unix timestamps in seconds
+type TimeQParam struct {
+	value time.Time
+	isSet bool
+}
+
+func (p *TimeQParam) IsQueryParam() bool {
+	return true
+}
+
+func (p *TimeQParam) AcceptedKind() string {
+	return "time"
+}
+
+// True if the request contained this param
+func (p *TimeQParam) Has() bool {
+	return p.isSet
+}
+
+func (p *TimeQParam) Get(defaultValue ...time.Time) time.Time {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *TimeQParam) Set(value string) *ParamParsingError {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		p.value = time.Unix(unix, 0)
+		p.isSet = true
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return invalidParamValue(fmt.Sprintf("expected a RFC 3339 date-time or a unix timestamp, got %q", value))
+	}
+
+	p.value = t
+	p.isSet = true
+	return nil
+}
+
+func (p *TimeQParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *TimeQParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.QueryParam(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+// Accepts durations in the Go format, e.g. `1h30m` or `250ms`
+type DurationQParam struct {
+	value time.Duration
+	isSet bool
+}
+
+func (p *DurationQParam) IsQueryParam() bool {
+	return true
+}
+
+func (p *DurationQParam) AcceptedKind() string {
+	return "duration"
+}
+
+// True if the request contained this param
+func (p *DurationQParam) Has() bool {
+	return p.isSet
+}
+
+func (p *DurationQParam) Get(defaultValue ...time.Duration) time.Duration {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *DurationQParam) Set(value string) *ParamParsingError {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return invalidParamValue(fmt.Sprintf("expected a duration (e.g. 1h30m), got %q", value))
+	}
+
+	p.value = d
+	p.isSet = true
+	return nil
+}
+
+func (p *DurationQParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *DurationQParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.QueryParam(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+// Enum is implemented by string types with a fixed set of allowed values, e.g.
+//
+//	type Status string
+//
+//	func (Status) Values() []Status { return []Status{"open", "closed"} }
+type Enum[T any] interface {
+	~string
+	Values() []T
+}
+
+// Accepts only the values listed by the Values() method of the enum type
+type EnumQParam[T Enum[T]] struct {
+	value T
+	isSet bool
+}
+
+func (p *EnumQParam[T]) IsQueryParam() bool {
+	return true
+}
+
+func (p *EnumQParam[T]) AcceptedKind() string {
+	return "enum: " + enumValuesList[T]()
+}
+
+// True if the request contained this param
+func (p *EnumQParam[T]) Has() bool {
+	return p.isSet
+}
+
+func (p *EnumQParam[T]) Get(defaultValue ...T) T {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *EnumQParam[T]) Set(value string) *ParamParsingError {
+	var zero T
+	if !slices.Contains(zero.Values(), T(value)) {
+		return invalidParamValue(fmt.Sprintf("expected one of: %s, got %q", enumValuesList[T](), value))
+	}
+
+	p.value = T(value)
+	p.isSet = true
+	return nil
+}
+
+func (p *EnumQParam[T]) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *EnumQParam[T]) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.QueryParam(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+func enumValuesList[T Enum[T]]() string {
+	var zero T
+	values := zero.Values()
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		list = append(list, string(v))
+	}
+	return strings.Join(list, ", ")
+}
+
+type ListItem interface {
+	~string | ~int | ~int64 | ~float64 | ~bool
+}
+
+// Collects all the values of a param, both repeated (`?tag=a&tag=b`) and comma-separated (`?tag=a,b`)
+type ListQParam[T ListItem] struct {
+	value []T
+	isSet bool
+}
+
+func (p *ListQParam[T]) IsQueryParam() bool {
+	return true
+}
+
+func (p *ListQParam[T]) AcceptedKind() string {
+	var zero T
+	return "[]" + reflect.TypeOf(zero).Kind().String()
+}
+
+// True if the request contained this param
+func (p *ListQParam[T]) Has() bool {
+	return p.isSet
+}
+
+func (p *ListQParam[T]) Get(defaultValue ...[]T) []T {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+// Parses a single, comma-separated value and appends the items to the list
+func (p *ListQParam[T]) Set(value string) *ParamParsingError {
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var parsed T
+		err := parseListItem(reflect.ValueOf(&parsed).Elem(), item)
+		if err != nil {
+			err.LogMessage = fmt.Sprintf("invalid item at position %d: %s", len(p.value), err.LogMessage)
+			return err
+		}
+
+		p.value = append(p.value, parsed)
+	}
+
+	p.isSet = true
+	return nil
+}
+
+func (p *ListQParam[T]) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *ListQParam[T]) Init(ctx RequestContext, name string) *ParamParsingError {
+	var values []string
+	if c, ok := ctx.(interface{ QueryParams() url.Values }); ok {
+		values = c.QueryParams()[name]
+	} else if v := ctx.QueryParam(name); v != "" {
+		values = []string{v}
+	}
+
+	for _, v := range values {
+		if err := p.Set(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseListItem(target reflect.Value, value string) *ParamParsingError {
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int, reflect.Int64:
+		num, err := parseIntParam(value)
+		if err != nil {
+			return err
+		}
+		target.SetInt(num)
+	case reflect.Float64:
+		num, err := parseFloatParam(value)
+		if err != nil {
+			return err
+		}
+		target.SetFloat(num)
+	case reflect.Bool:
+		b, err := parseBoolParam(value)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	}
+	return nil
+}
+
 // #endregion Query Params
 
 // #region URL Params
@@ -147,6 +487,18 @@ type StringUrlParam struct {
 	isSet bool
 }
 
+func (p *StringUrlParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *StringUrlParam) ParamLocation() string {
+	return "path"
+}
+
+func (p *StringUrlParam) ParamName(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
 func (p *StringUrlParam) AcceptedKind() string {
 	return reflect.String.String()
 }
@@ -161,6 +513,10 @@ func (p *StringUrlParam) Set(value string) *ParamParsingError {
 	return nil
 }
 
+func (p *StringUrlParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
 func (p *StringUrlParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	v := ctx.Param(name)
 	if v != "" {
@@ -174,6 +530,18 @@ type NumberUrlParam struct {
 	isSet bool
 }
 
+func (p *NumberUrlParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *NumberUrlParam) ParamLocation() string {
+	return "path"
+}
+
+func (p *NumberUrlParam) ParamName(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
 func (p *NumberUrlParam) AcceptedKind() string {
 	return reflect.Int64.String()
 }
@@ -185,7 +553,7 @@ func (p *NumberUrlParam) Get() int64 {
 func (p *NumberUrlParam) Set(value string) *ParamParsingError {
 	num, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return &ParamParsingError{400, "Bad Request", "parsing to number failed", ""}
+		return &ParamParsingError{400, "Bad Request", "parsing to number failed", "", false}
 	}
 
 	p.value = num
@@ -193,6 +561,10 @@ func (p *NumberUrlParam) Set(value string) *ParamParsingError {
 	return nil
 }
 
+func (p *NumberUrlParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
 func (p *NumberUrlParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	v := ctx.Param(name)
 	if v != "" {
@@ -206,6 +578,18 @@ type BoolUrlParam struct {
 	isSet bool
 }
 
+func (p *BoolUrlParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *BoolUrlParam) ParamLocation() string {
+	return "path"
+}
+
+func (p *BoolUrlParam) ParamName(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
 func (p *BoolUrlParam) AcceptedKind() string {
 	return reflect.Bool.String()
 }
@@ -220,6 +604,10 @@ func (p *BoolUrlParam) Set(value string) *ParamParsingError {
 	return nil
 }
 
+func (p *BoolUrlParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
 func (p *BoolUrlParam) Init(ctx RequestContext, name string) *ParamParsingError {
 	v := ctx.Param(name)
 	if v != "" {
@@ -228,58 +616,703 @@ func (p *BoolUrlParam) Init(ctx RequestContext, name string) *ParamParsingError
 	return nil
 }
 
-// #endregion
-
-type ParamParsingError struct {
-	StatusCode int
-	Message    string
-	LogMessage string
-	paramName  string
+type UUIDUrlParam struct {
+	value uuid.UUID
+	isSet bool
 }
 
-func (e *ParamParsingError) Response() *Response {
-	resp := &Response{
-		Status: e.StatusCode,
-	}
-	if len(e.Message) > 0 {
-		resp.Text(e.Message)
-	}
-	return resp
+func (p *UUIDUrlParam) IsQueryParam() bool {
+	return false
 }
 
-func (e *ParamParsingError) ToString() string {
-	return fmt.Sprintf("%s: [param='%s'] %s", e.Message, e.paramName, e.LogMessage)
+func (p *UUIDUrlParam) ParamLocation() string {
+	return "path"
 }
 
-type RequestContext interface {
-	Path() string
-	Param(name string) string
-	QueryParam(name string) string
-	Cookie(name string) (*http.Cookie, error)
+func (p *UUIDUrlParam) ParamName(fieldName string) string {
+	return strings.ToLower(fieldName)
 }
 
-type paramBinder[T any] func(ctx RequestContext) (T, *ParamParsingError)
+func (p *UUIDUrlParam) AcceptedKind() string {
+	return "uuid"
+}
 
-type internalParamBinder struct {
-	paramName string
-	bind      func(rval reflect.Value, ctx RequestContext) *ParamParsingError
+func (p *UUIDUrlParam) Get() uuid.UUID {
+	return p.value
 }
 
-func CreateSearchParamsBinder[T any]() paramBinder[T] {
-	var paramsType T
-	paramsT := reflect.TypeOf(paramsType)
-	if paramsT.Kind() == reflect.Pointer {
-		paramsT = paramsT.Elem()
+func (p *UUIDUrlParam) Set(value string) *ParamParsingError {
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return invalidParamValue(fmt.Sprintf("expected a UUID, got %q", value))
 	}
 
-	paramInterface := reflect.TypeOf((*SearchQParam)(nil)).Elem()
+	p.value = id
+	p.isSet = true
+	return nil
+}
 
-	paramKeys := make([]internalParamBinder, 0, paramsT.NumField())
-	for i := range paramsT.NumField() {
+func (p *UUIDUrlParam) paramValue() (any, bool) {
+	return p.value.String(), p.isSet
+}
+
+func (p *UUIDUrlParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.Param(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+// #endregion
+
+// #region Header Params
+
+// Header params are named after the struct field, converted to the header format, e.g.
+// `XRequestID` becomes `X-Request-ID`
+
+type StringHeaderParam struct {
+	value string
+	isSet bool
+}
+
+func (p *StringHeaderParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *StringHeaderParam) ParamLocation() string {
+	return "header"
+}
+
+func (p *StringHeaderParam) ParamName(fieldName string) string {
+	return headerNameFromField(fieldName)
+}
+
+func (p *StringHeaderParam) AcceptedKind() string {
+	return reflect.String.String()
+}
+
+// True if the request contained this header
+func (p *StringHeaderParam) Has() bool {
+	return p.isSet
+}
+
+func (p *StringHeaderParam) Get(defaultValue ...string) string {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *StringHeaderParam) Set(value string) *ParamParsingError {
+	p.value = value
+	p.isSet = true
+	return nil
+}
+
+func (p *StringHeaderParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *StringHeaderParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := headerValue(ctx, name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+type NumberHeaderParam struct {
+	value int64
+	isSet bool
+}
+
+func (p *NumberHeaderParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *NumberHeaderParam) ParamLocation() string {
+	return "header"
+}
+
+func (p *NumberHeaderParam) ParamName(fieldName string) string {
+	return headerNameFromField(fieldName)
+}
+
+func (p *NumberHeaderParam) AcceptedKind() string {
+	return reflect.Int64.String()
+}
+
+// True if the request contained this header
+func (p *NumberHeaderParam) Has() bool {
+	return p.isSet
+}
+
+func (p *NumberHeaderParam) Get(defaultValue ...int64) int64 {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *NumberHeaderParam) Set(value string) *ParamParsingError {
+	num, err := parseIntParam(value)
+	if err != nil {
+		return err
+	}
+
+	p.value = num
+	p.isSet = true
+	return nil
+}
+
+func (p *NumberHeaderParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *NumberHeaderParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := headerValue(ctx, name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+type BoolHeaderParam struct {
+	value bool
+	isSet bool
+}
+
+func (p *BoolHeaderParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *BoolHeaderParam) ParamLocation() string {
+	return "header"
+}
+
+func (p *BoolHeaderParam) ParamName(fieldName string) string {
+	return headerNameFromField(fieldName)
+}
+
+func (p *BoolHeaderParam) AcceptedKind() string {
+	return reflect.Bool.String()
+}
+
+// True if the request contained this header
+func (p *BoolHeaderParam) Has() bool {
+	return p.isSet
+}
+
+func (p *BoolHeaderParam) Get(defaultValue ...bool) bool {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *BoolHeaderParam) Set(value string) *ParamParsingError {
+	p.value = value == "1" || strings.ToLower(value) == "true"
+	p.isSet = true
+	return nil
+}
+
+func (p *BoolHeaderParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *BoolHeaderParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := headerValue(ctx, name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+func headerValue(ctx RequestContext, name string) string {
+	if c, ok := ctx.(interface{ Request() *http.Request }); ok {
+		return c.Request().Header.Get(name)
+	}
+	return ""
+}
+
+// converts a Go field name to a header name, e.g. `IfNoneMatch` to `If-None-Match` and `XRequestID` to `X-Request-ID`
+func headerNameFromField(fieldName string) string {
+	runes := []rune(fieldName)
+	var b strings.Builder
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// #endregion Header Params
+
+// #region Cookie Params
+
+type CookieParam struct {
+	value string
+	isSet bool
+}
+
+func (p *CookieParam) IsQueryParam() bool {
+	return false
+}
+
+func (p *CookieParam) ParamLocation() string {
+	return "cookie"
+}
+
+func (p *CookieParam) ParamName(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
+func (p *CookieParam) AcceptedKind() string {
+	return reflect.String.String()
+}
+
+// True if the request contained this cookie
+func (p *CookieParam) Has() bool {
+	return p.isSet
+}
+
+func (p *CookieParam) Get(defaultValue ...string) string {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *CookieParam) Set(value string) *ParamParsingError {
+	p.value = value
+	p.isSet = true
+	return nil
+}
+
+func (p *CookieParam) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *CookieParam) Init(ctx RequestContext, name string) *ParamParsingError {
+	cookie, err := ctx.Cookie(name)
+	if err == nil && cookie != nil && cookie.Value != "" {
+		return p.Set(cookie.Value)
+	}
+	return nil
+}
+
+// #endregion Cookie Params
+
+// #region Custom Params
+
+// Parser converts the raw value of a param into T. Returned errors are sent to the client as the reason
+// the param is invalid.
+type Parser[T any] func(value string) (T, error)
+
+var paramParsers sync.Map
+
+// Registers the parser used by the QParam[T] and UrlParam[T] params, e.g.
+// `butler.RegisterParamParser(netip.ParseAddr)`.
+//
+// Types implementing encoding.TextUnmarshaler (like time.Time or netip.Addr) can be used without
+// registering a parser, a registered parser takes precedence over the UnmarshalText method.
+func RegisterParamParser[T any](parser Parser[T]) {
+	if parser == nil {
+		panic("param parser cannot be nil")
+	}
+	paramParsers.Store(reflect.TypeFor[T](), parser)
+}
+
+func parserFor[T any]() (Parser[T], error) {
+	t := reflect.TypeFor[T]()
+	if parser, ok := paramParsers.Load(t); ok {
+		return parser.(Parser[T]), nil
+	}
+
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		return func(value string) (T, error) {
+			var v T
+			err := any(&v).(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+			return v, err
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no parser registered for the param type %s, use RegisterParamParser or implement encoding.TextUnmarshaler", t)
+}
+
+func parseCustomParam[T any](value string) (T, *ParamParsingError) {
+	parser, err := parserFor[T]()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	v, err := parser(value)
+	if err != nil {
+		return v, invalidParamValue(err.Error())
+	}
+	return v, nil
+}
+
+// implemented by the params using the registered parsers, allows to detect the missing parsers when the
+// binder is created instead of when the first request comes in
+type parsedParam interface {
+	checkParser() error
+}
+
+// Query param of any type that has a registered Parser or implements encoding.TextUnmarshaler
+type QParam[T any] struct {
+	value T
+	isSet bool
+}
+
+func (p *QParam[T]) IsQueryParam() bool {
+	return true
+}
+
+func (p *QParam[T]) AcceptedKind() string {
+	return reflect.TypeFor[T]().String()
+}
+
+// True if the request contained this query param
+func (p *QParam[T]) Has() bool {
+	return p.isSet
+}
+
+func (p *QParam[T]) Get(defaultValue ...T) T {
+	if !p.isSet && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return p.value
+}
+
+func (p *QParam[T]) Set(value string) *ParamParsingError {
+	v, err := parseCustomParam[T](value)
+	if err != nil {
+		return err
+	}
+
+	p.value = v
+	p.isSet = true
+	return nil
+}
+
+func (p *QParam[T]) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *QParam[T]) checkParser() error {
+	_, err := parserFor[T]()
+	return err
+}
+
+func (p *QParam[T]) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.QueryParam(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+// URL param of any type that has a registered Parser or implements encoding.TextUnmarshaler
+type UrlParam[T any] struct {
+	value T
+	isSet bool
+}
+
+func (p *UrlParam[T]) IsQueryParam() bool {
+	return false
+}
+
+func (p *UrlParam[T]) ParamLocation() string {
+	return "path"
+}
+
+func (p *UrlParam[T]) ParamName(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
+func (p *UrlParam[T]) AcceptedKind() string {
+	return reflect.TypeFor[T]().String()
+}
+
+func (p *UrlParam[T]) Get() T {
+	return p.value
+}
+
+func (p *UrlParam[T]) Set(value string) *ParamParsingError {
+	v, err := parseCustomParam[T](value)
+	if err != nil {
+		return err
+	}
+
+	p.value = v
+	p.isSet = true
+	return nil
+}
+
+func (p *UrlParam[T]) paramValue() (any, bool) {
+	return p.value, p.isSet
+}
+
+func (p *UrlParam[T]) checkParser() error {
+	_, err := parserFor[T]()
+	return err
+}
+
+func (p *UrlParam[T]) Init(ctx RequestContext, name string) *ParamParsingError {
+	v := ctx.Param(name)
+	if v != "" {
+		return p.Set(v)
+	}
+	return nil
+}
+
+// #endregion Custom Params
+
+type ParamParsingError struct {
+	StatusCode int
+	Message    string
+	LogMessage string
+	paramName  string
+	missing    bool
+}
+
+func (e *ParamParsingError) Response() *Response {
+	problem := NewProblem(e.StatusCode, e.detail())
+	if len(e.Message) > 0 {
+		problem.Title = e.Message
+	}
+	if e.paramName != "" {
+		problem.With("param", e.paramName)
+	}
+	return Respond.FromProblem(problem)
+}
+
+func (e *ParamParsingError) detail() string {
+	switch {
+	case e.paramName == "":
+		return e.LogMessage
+	case e.missing:
+		return fmt.Sprintf("the `%s` param is required", e.paramName)
+	default:
+		return fmt.Sprintf("invalid value of the `%s` param: %s", e.paramName, e.LogMessage)
+	}
+}
+
+// All the errors encountered while binding the params of a request
+type ParamParsingErrors []*ParamParsingError
+
+type paramErrorEntry struct {
+	Param  string `json:"param"`
+	Detail string `json:"detail"`
+}
+
+// A single error is sent as is, multiple errors are combined into a 400 problem listing every
+// invalid param in the `errors` member
+func (e ParamParsingErrors) Response() *Response {
+	if len(e) == 1 {
+		return e[0].Response()
+	}
+
+	entries := make([]paramErrorEntry, 0, len(e))
+	for _, perr := range e {
+		entries = append(entries, paramErrorEntry{Param: perr.paramName, Detail: perr.detail()})
+	}
+	return Respond.FromProblem(NewProblem(400, "one or more params are invalid").With("errors", entries))
+}
+
+func (e ParamParsingErrors) ToString() string {
+	messages := make([]string, 0, len(e))
+	for _, perr := range e {
+		messages = append(messages, perr.ToString())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func invalidParamValue(message string) *ParamParsingError {
+	return &ParamParsingError{400, "Bad Request", message, "", false}
+}
+
+func parseIntParam(value string) (int64, *ParamParsingError) {
+	num, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, invalidParamValue(fmt.Sprintf("expected an integer, got %q", value))
+	}
+	return num, nil
+}
+
+func parseFloatParam(value string) (float64, *ParamParsingError) {
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, invalidParamValue(fmt.Sprintf("expected a number, got %q", value))
+	}
+	return num, nil
+}
+
+func parseBoolParam(value string) (bool, *ParamParsingError) {
+	switch strings.ToLower(value) {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	}
+	return false, invalidParamValue(fmt.Sprintf("expected a boolean, got %q", value))
+}
+
+func (e *ParamParsingError) ToString() string {
+	return fmt.Sprintf("%s: [param='%s'] %s", e.Message, e.paramName, e.LogMessage)
+}
+
+type RequestContext interface {
+	Path() string
+	Param(name string) string
+	QueryParam(name string) string
+	Cookie(name string) (*http.Cookie, error)
+}
+
+type paramBinder[T any] func(ctx RequestContext) (T, ParamParsingErrors)
+
+type internalParamBinder struct {
+	paramName string
+	bind      func(rval reflect.Value, ctx RequestContext) *ParamParsingError
+}
+
+type paramNamer interface {
+	ParamName(fieldName string) string
+}
+
+// Options of a param field, read from the `param` and `default` struct tags,
+// e.g. `param:"page_size,required" default:"20"`
+type paramTag struct {
+	name         string
+	required     bool
+	defaultValue string
+	hasDefault   bool
+}
+
+// Parses the `param` and `default` tags of the field, panics if the tags are invalid
+func paramTagOf(field reflect.StructField) paramTag {
+	var tag paramTag
+	tag.defaultValue, tag.hasDefault = field.Tag.Lookup("default")
+
+	options := strings.Split(field.Tag.Get("param"), ",")
+	tag.name = strings.TrimSpace(options[0])
+	for _, option := range options[1:] {
+		switch strings.TrimSpace(option) {
+		case "required":
+			tag.required = true
+		default:
+			panic(fmt.Sprintf("invalid param tag on field %s: unknown option %q", field.Name, option))
+		}
+	}
+
+	if tag.required && tag.hasDefault {
+		panic(fmt.Sprintf("invalid param tag on field %s: a required param cannot have a default value", field.Name))
+	}
+
+	return tag
+}
+
+// Name under which the param of the given field is looked up in the request
+func paramNameOf(field reflect.StructField) string {
+	if name := paramTagOf(field).name; name != "" {
+		return name
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		if namer, ok := reflect.New(t.Elem()).Interface().(paramNamer); ok {
+			return namer.ParamName(field.Name)
+		}
+	}
+	return strings.ToLower(field.Name)
+}
+
+// implemented by the params that can be marked as required or given a default value
+type optionalParam interface {
+	Set(value string) *ParamParsingError
+	paramValue() (value any, isSet bool)
+}
+
+var optionalParamInterface = reflect.TypeFor[optionalParam]()
+
+// Applies the default value or reports the missing param if it was not present in the request
+func applyParamTag(qParam SearchQParam, tag paramTag) *ParamParsingError {
+	if !tag.required && !tag.hasDefault {
+		return nil
+	}
+
+	p := qParam.(optionalParam)
+	if _, isSet := p.paramValue(); isSet {
+		return nil
+	}
+
+	if tag.required {
+		return &ParamParsingError{400, "Bad Request", "the param is required", "", true}
+	}
+	return p.Set(tag.defaultValue)
+}
+
+func CreateSearchParamsBinder[T any]() paramBinder[T] {
+	return createParamsBinder[T](false)
+}
+
+// When skipListParams is set, the pagination, sorting and filtering params are left at their zero values
+func createParamsBinder[T any](skipListParams bool) paramBinder[T] {
+	var paramsType T
+	paramsT := reflect.TypeOf(paramsType)
+	if paramsT.Kind() == reflect.Pointer {
+		paramsT = paramsT.Elem()
+	}
+
+	paramInterface := reflect.TypeOf((*SearchQParam)(nil)).Elem()
+
+	paramKeys := make([]internalParamBinder, 0, paramsT.NumField())
+	for i := range paramsT.NumField() {
 		field := paramsT.Field(i)
 		if field.Type.Implements(paramInterface) {
 			fname := field.Name
-			paramName := strings.ToLower(fname)
+			paramName := paramNameOf(field)
+			tag := paramTagOf(field)
+
+			fieldTag := field.Tag
+			var configurable, skip bool
+			if field.Type.Kind() == reflect.Pointer {
+				sample := reflect.New(field.Type.Elem()).Interface()
+				if parsed, ok := sample.(parsedParam); ok {
+					if err := parsed.checkParser(); err != nil {
+						panic(err.Error())
+					}
+				}
+				if c, ok := sample.(configurableParam); ok {
+					// panics early if the tags are invalid
+					c.configure(field.Tag)
+					configurable = true
+				}
+				_, isListParam := sample.(listParam)
+				skip = skipListParams && isListParam
+			}
+
+			if tag.required || tag.hasDefault {
+				if !field.Type.Implements(optionalParamInterface) {
+					panic(fmt.Sprintf("param %s does not support required or default values", fname))
+				}
+				if tag.hasDefault && field.Type.Kind() == reflect.Pointer {
+					p := reflect.New(field.Type.Elem()).Interface().(optionalParam)
+					if err := p.Set(tag.defaultValue); err != nil {
+						panic(fmt.Sprintf("invalid default value of the `%s` param: %s", paramName, err.LogMessage))
+					}
+				}
+			}
 
 			if field.Type.Kind() != reflect.Pointer {
 				paramKeys = append(paramKeys, internalParamBinder{
@@ -288,7 +1321,10 @@ func CreateSearchParamsBinder[T any]() paramBinder[T] {
 						field := rval.FieldByName(fname)
 						fieldValue := field.Interface()
 						qParam := fieldValue.(SearchQParam)
-						return qParam.Init(ctx, paramName)
+						if err := qParam.Init(ctx, paramName); err != nil {
+							return err
+						}
+						return applyParamTag(qParam, tag)
 					},
 				})
 			} else {
@@ -299,8 +1335,17 @@ func CreateSearchParamsBinder[T any]() paramBinder[T] {
 						v := reflect.New(field.Type().Elem())
 						field.Set(v)
 						fieldValue := v.Interface()
+						if configurable {
+							fieldValue.(configurableParam).configure(fieldTag)
+						}
+						if skip {
+							return nil
+						}
 						qParam := fieldValue.(SearchQParam)
-						return qParam.Init(ctx, paramName)
+						if err := qParam.Init(ctx, paramName); err != nil {
+							return err
+						}
+						return applyParamTag(qParam, tag)
 					},
 				})
 			}
@@ -309,18 +1354,21 @@ func CreateSearchParamsBinder[T any]() paramBinder[T] {
 		}
 	}
 
-	return func(ctx RequestContext) (T, *ParamParsingError) {
+	return func(ctx RequestContext) (T, ParamParsingErrors) {
 		var params T
 		paramsT := reflect.ValueOf(&params).Elem()
 
+		var errs ParamParsingErrors
 		for _, binder := range paramKeys {
 			err := binder.bind(paramsT, ctx)
 			if err != nil {
-				err.paramName = binder.paramName
-				return params, err
+				if err.paramName == "" {
+					err.paramName = binder.paramName
+				}
+				errs = append(errs, err)
 			}
 		}
 
-		return params, nil
+		return params, errs
 	}
 }
@@ -1,10 +1,17 @@
 package butler_test
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	f "github.com/ncpa0cpl/butler"
+	"github.com/ncpa0cpl/butler/swag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,3 +107,313 @@ func TestParamsBinder(t *testing.T) {
 	assert.Equal(true, params.IncludeDel.Get())
 	assert.Equal("", params.NotProvided.Get())
 }
+
+type ticketStatus string
+
+func (ticketStatus) Values() []ticketStatus {
+	return []ticketStatus{"open", "closed"}
+}
+
+func TestTypedSearchParams(t *testing.T) {
+	assert := assert.New(t)
+
+	floatParam := f.FloatQParam{}
+	assert.Nil(floatParam.Set("1.5"))
+	assert.Equal(1.5, floatParam.Get())
+	assert.Equal(`expected a number, got "abc"`, floatParam.Set("abc").LogMessage)
+
+	timeParam := f.TimeQParam{}
+	assert.Nil(timeParam.Set("2024-05-01T10:00:00Z"))
+	assert.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), timeParam.Get().UTC())
+	assert.Nil(timeParam.Set("1714557600"))
+	assert.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), timeParam.Get().UTC())
+	assert.NotNil(timeParam.Set("yesterday"))
+
+	durationParam := f.DurationQParam{}
+	assert.Equal(time.Minute, durationParam.Get(time.Minute))
+	assert.Nil(durationParam.Set("1h30m"))
+	assert.Equal(90*time.Minute, durationParam.Get())
+
+	uuidParam := f.UUIDUrlParam{}
+	assert.Nil(uuidParam.Set("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	assert.Equal("6ba7b810-9dad-11d1-80b4-00c04fd430c8", uuidParam.Get().String())
+	assert.Equal(`expected a UUID, got "123"`, uuidParam.Set("123").LogMessage)
+
+	enumParam := f.EnumQParam[ticketStatus]{}
+	assert.Nil(enumParam.Set("open"))
+	assert.Equal(ticketStatus("open"), enumParam.Get())
+	assert.Equal(`expected one of: open, closed, got "pending"`, enumParam.Set("pending").LogMessage)
+	assert.Equal("enum: open, closed", enumParam.AcceptedKind())
+
+	listParam := f.ListQParam[int64]{}
+	assert.Nil(listParam.Set("1, 2"))
+	assert.Nil(listParam.Set("3"))
+	assert.Equal([]int64{1, 2, 3}, listParam.Get())
+	assert.Equal(`invalid item at position 4: expected an integer, got "x"`, listParam.Set("4,x").LogMessage)
+	assert.Equal("[]int64", listParam.AcceptedKind())
+}
+
+func TestListParamBinding(t *testing.T) {
+	assert := assert.New(t)
+
+	type ticketParams struct {
+		Tag    *f.ListQParam[string]
+		Status *f.EnumQParam[ticketStatus]
+		Flags  *f.ListQParam[bool]
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[ticketParams]{
+		Method: "GET",
+		Path:   "/tickets",
+		Handler: func(request *f.Request, params ticketParams) *f.Response {
+			return f.Respond.Ok().JSON(map[string]any{
+				"tags":   params.Tag.Get(),
+				"status": params.Status.Get("open"),
+				"flags":  params.Flags.Get(),
+			})
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/tickets?tag=a&tag=b,c&flags=1,FALSE,true", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"tags":["a","b","c"],"status":"open","flags":[true,false,true]}`, string(body))
+
+	_, resp = request("GET", baseUrl+"/tickets?status=pending", nil)
+	assert.Equal(400, resp.StatusCode)
+
+	body, resp = request("GET", baseUrl+"/tickets?flags=true,yes", nil)
+	assert.Equal(400, resp.StatusCode)
+	assert.Contains(string(body), `expected a boolean, got \"yes\"`)
+}
+
+func TestHeaderAndCookieParams(t *testing.T) {
+	assert := assert.New(t)
+
+	type tracedParams struct {
+		XRequestID  *f.StringHeaderParam
+		IfNoneMatch *f.StringHeaderParam
+		XRetries    *f.NumberHeaderParam
+		Session     *f.CookieParam
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[tracedParams]{
+		Method: "GET",
+		Path:   "/traced",
+		Handler: func(request *f.Request, params tracedParams) *f.Response {
+			return f.Respond.Ok().JSON(map[string]any{
+				"requestId":  params.XRequestID.Get(),
+				"hasEtag":    params.IfNoneMatch.Has(),
+				"etag":       params.IfNoneMatch.Get("none"),
+				"retries":    params.XRetries.Get(3),
+				"session":    params.Session.Get(),
+				"hasSession": params.Session.Has(),
+			})
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request(
+		"GET", baseUrl+"/traced", nil,
+		header{"X-Request-ID", "abc"},
+		header{"X-Retries", "5"},
+		header{"Cookie", "session=s3cr3t"},
+	)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{
+		"requestId": "abc",
+		"hasEtag": false,
+		"etag": "none",
+		"retries": 5,
+		"session": "s3cr3t",
+		"hasSession": true
+	}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/traced", nil, header{"If-None-Match", `"v1"`})
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{
+		"requestId": "",
+		"hasEtag": true,
+		"etag": "\"v1\"",
+		"retries": 3,
+		"session": "",
+		"hasSession": false
+	}`, string(body))
+
+	_, resp = request("GET", baseUrl+"/traced", nil, header{"X-Retries", "many"})
+	assert.Equal(400, resp.StatusCode)
+}
+
+func TestParamTags(t *testing.T) {
+	assert := assert.New(t)
+
+	type pageParams struct {
+		PageSize *f.NumberQParam      `param:"page_size" default:"20"`
+		Cursor   *f.StringQParam      `param:"cursor,required"`
+		Order    *f.StringQParam      `param:",required"`
+		Tenant   *f.StringHeaderParam `param:"X-Tenant"`
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[pageParams]{
+		Method: "GET",
+		Path:   "/pages",
+		Handler: func(request *f.Request, params pageParams) *f.Response {
+			return f.Respond.Ok().JSON(map[string]any{
+				"pageSize": params.PageSize.Get(),
+				"cursor":   params.Cursor.Get(),
+				"order":    params.Order.Get(),
+				"tenant":   params.Tenant.Get(),
+			})
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/pages?cursor=abc&order=asc", nil, header{"X-Tenant", "acme"})
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"pageSize":20,"cursor":"abc","order":"asc","tenant":"acme"}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/pages?cursor=abc&order=asc&page_size=5&pagesize=7", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"pageSize":5,"cursor":"abc","order":"asc","tenant":""}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/pages?order=asc", nil)
+	assert.Equal(400, resp.StatusCode)
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "the `+"`cursor`"+` param is required",
+		"param": "cursor"
+	}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/pages?page_size=many", nil)
+	assert.Equal(400, resp.StatusCode)
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "one or more params are invalid",
+		"errors": [
+			{"param": "page_size", "detail": "invalid value of the `+"`page_size`"+` param: parsing to number failed"},
+			{"param": "cursor", "detail": "the `+"`cursor`"+` param is required"},
+			{"param": "order", "detail": "the `+"`order`"+` param is required"}
+		]
+	}`, string(body))
+}
+
+func TestParamTagsDocumentation(t *testing.T) {
+	assert := assert.New(t)
+
+	type userParams struct {
+		ID       *f.StringUrlParam `param:"user_id"`
+		Org      *f.UrlParam[string]
+		PageSize *f.NumberQParam `param:"page_size,required"`
+	}
+
+	params := swag.NewParamsTypeStructure(userParams{})
+
+	describe := func(params []swag.TypeStructure) []string {
+		described := []string{}
+		for _, p := range params {
+			described = append(described, fmt.Sprintf("%s %s required=%v", p.Name, p.Kind, p.Required))
+		}
+		return described
+	}
+
+	assert.Equal([]string{"user_id string required=true", "org string required=true"}, describe(params.ParamsIn("path")))
+	assert.Equal([]string{"page_size int64 required=true"}, describe(params.ParamsIn("query")))
+}
+
+func TestInvalidParamTags(t *testing.T) {
+	assert := assert.New(t)
+
+	type unknownOption struct {
+		Page *f.NumberQParam `param:"page,optional"`
+	}
+	type badDefault struct {
+		Page *f.NumberQParam `default:"first"`
+	}
+	type requiredWithDefault struct {
+		Page *f.NumberQParam `param:",required" default:"1"`
+	}
+
+	assert.PanicsWithValue(`invalid param tag on field Page: unknown option "optional"`, func() {
+		f.CreateSearchParamsBinder[unknownOption]()
+	})
+	assert.PanicsWithValue("invalid default value of the `page` param: parsing to number failed", func() {
+		f.CreateSearchParamsBinder[badDefault]()
+	})
+	assert.PanicsWithValue("invalid param tag on field Page: a required param cannot have a default value", func() {
+		f.CreateSearchParamsBinder[requiredWithDefault]()
+	})
+}
+
+type userID int64
+
+func parseUserID(value string) (userID, error) {
+	id, ok := strings.CutPrefix(value, "usr_")
+	if !ok {
+		return 0, errors.New("expected an id starting with usr_")
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	return userID(n), err
+}
+
+func TestCustomParams(t *testing.T) {
+	assert := assert.New(t)
+
+	f.RegisterParamParser(parseUserID)
+	f.RegisterParamParser(netip.ParseAddr)
+
+	type userParams struct {
+		ID    *f.UrlParam[userID]
+		From  *f.QParam[netip.Addr]
+		Since *f.QParam[time.Time] `param:"since" default:"2024-01-01T00:00:00Z"`
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[userParams]{
+		Method: "GET",
+		Path:   "/users/:id",
+		Handler: func(request *f.Request, params userParams) *f.Response {
+			return f.Respond.Ok().JSON(map[string]any{
+				"id":    params.ID.Get(),
+				"from":  params.From.Get(netip.IPv4Unspecified()).String(),
+				"since": params.Since.Get().UTC().Format(time.RFC3339),
+			})
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/users/usr_42?from=10.0.0.1&since=2025-03-01T12:00:00Z", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"id":42,"from":"10.0.0.1","since":"2025-03-01T12:00:00Z"}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/users/usr_7", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.JSONEq(`{"id":7,"from":"0.0.0.0","since":"2024-01-01T00:00:00Z"}`, string(body))
+
+	body, resp = request("GET", baseUrl+"/users/42", nil)
+	assert.Equal(400, resp.StatusCode)
+	assert.Contains(string(body), "invalid value of the `id` param: expected an id starting with usr_")
+
+	assert.Equal("netip.Addr", (&f.QParam[netip.Addr]{}).AcceptedKind())
+
+	type unknownParams struct {
+		Color *f.QParam[struct{ R, G, B uint8 }]
+	}
+	assert.Panics(func() {
+		f.CreateSearchParamsBinder[unknownParams]()
+	})
+}
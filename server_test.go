@@ -0,0 +1,363 @@
+package butler_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Port = 8081
+
+	slow := &f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/slow",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			time.Sleep(time.Second / 2)
+			return f.Respond.Ok().Text("done")
+		},
+	}
+
+	server.Add(slow)
+
+	var hookCalled atomic.Bool
+	server.OnShutdown(func() {
+		hookCalled.Store(true)
+	})
+
+	go server.Listen()
+	waitUntil(func() bool { return len(server.Addrs()) > 0 })
+
+	type result struct {
+		status int
+		body   string
+	}
+	results := make(chan result, 1)
+	go func() {
+		body, resp := request("GET", "http://localhost:8081/slow", nil)
+		results <- result{resp.StatusCode, string(body)}
+	}()
+
+	// give the request time to reach the handler
+	time.Sleep(time.Second / 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	assert.Nil(err)
+	assert.True(hookCalled.Load())
+
+	res := <-results
+	assert.Equal(200, res.status)
+	assert.Equal("done", res.body)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Port = 8081
+
+	slow := &f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/slow",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			time.Sleep(2 * time.Second)
+			return f.Respond.Ok().Text("done")
+		},
+	}
+
+	server.Add(slow)
+
+	hookCalls := 0
+	server.OnShutdown(func() {
+		hookCalls++
+	})
+
+	go server.Listen()
+	waitUntil(func() bool { return len(server.Addrs()) > 0 })
+
+	go func() {
+		defer func() { recover() }()
+		request("GET", "http://localhost:8081/slow", nil)
+	}()
+
+	time.Sleep(time.Second / 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second/10)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	server.Close()
+	assert.Equal(1, hookCalls)
+}
+
+func writeSelfSignedCert(dir string, commonName string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noErr(err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	noErr(err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	noErr(err)
+
+	certFile = path.Join(dir, "cert.pem")
+	keyFile = path.Join(dir, "key.pem")
+	noErr(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	noErr(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestTLSListener(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSignedCert(dir, "first")
+
+	server := f.CreateServer()
+	server.Port = 8443
+	server.TLS = &f.TLSSettings{
+		CertFile:         certFile,
+		KeyFile:          keyFile,
+		ReloadInterval:   time.Millisecond,
+		RedirectFromPort: 8082,
+	}
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/secure",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text(request.HttpRequest().Proto)
+		},
+	})
+
+	go server.Listen()
+	defer server.Close()
+	waitUntil(func() bool { return len(server.Addrs()) > 0 })
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get("https://localhost:8443/secure")
+	noErr(err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("HTTP/2.0", string(body))
+	assert.Equal("first", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// certificate should be picked up without restarting the server
+	time.Sleep(10 * time.Millisecond)
+	writeSelfSignedCert(dir, "second")
+	time.Sleep(10 * time.Millisecond)
+
+	resp, err = client.Get("https://localhost:8443/secure")
+	noErr(err)
+	assert.Equal("second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	waitUntil(func() bool {
+		conn, err := net.Dial("tcp", "localhost:8082")
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+	resp, err = client.Get("http://localhost:8082/secure?foo=bar")
+	noErr(err)
+	assert.Equal(308, resp.StatusCode)
+	assert.Equal("https://localhost:8443/secure?foo=bar", resp.Header.Get("Location"))
+}
+
+func TestTLSRedirectToListenerPort(t *testing.T) {
+	assert := assert.New(t)
+
+	certFile, keyFile := writeSelfSignedCert(t.TempDir(), "redirect")
+
+	server := f.CreateServer()
+	server.TLS = &f.TLSSettings{
+		CertFile:         certFile,
+		KeyFile:          keyFile,
+		RedirectFromPort: 8083,
+	}
+	server.Addresses = []string{"127.0.0.1:0"}
+
+	go server.Listen()
+	defer server.Close()
+	waitUntil(func() bool { return len(server.Addrs()) > 0 })
+
+	_, port, err := net.SplitHostPort(server.Addrs()[0].String())
+	noErr(err)
+
+	waitUntil(func() bool {
+		conn, err := net.Dial("tcp", "localhost:8083")
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get("http://localhost:8083/secure")
+	noErr(err)
+	assert.Equal(308, resp.StatusCode)
+	assert.Equal("https://localhost:"+port+"/secure", resp.Header.Get("Location"))
+}
+
+func TestH2CListener(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Port = 8081
+	server.H2C = true
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/proto",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text(request.HttpRequest().Proto)
+		},
+	})
+
+	go server.Listen()
+	defer server.Close()
+	waitUntil(func() bool { return len(server.Addrs()) > 0 })
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://localhost:8081/proto")
+	noErr(err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("HTTP/2.0", string(body))
+}
+
+func TestListenOnMultipleAddresses(t *testing.T) {
+	assert := assert.New(t)
+
+	socketPath := path.Join(t.TempDir(), "butler.sock")
+
+	// leave a stale socket file behind, as if a previous process crashed
+	stale, err := net.Listen("unix", socketPath)
+	noErr(err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	server := f.CreateServer()
+	server.Addresses = []string{"127.0.0.1:0", "unix:" + socketPath}
+	server.UnixSocketMode = 0600
+
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/hello",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text("hello")
+		},
+	})
+
+	go server.Listen()
+	defer server.Close()
+	waitUntil(func() bool { return len(server.Addrs()) == 2 })
+
+	tcpAddr := server.Addrs()[0].(*net.TCPAddr)
+	assert.NotEqual(0, tcpAddr.Port)
+
+	body, resp := request("GET", fmt.Sprintf("http://%s/hello", tcpAddr), nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("hello", string(body))
+
+	stat, err := os.Stat(socketPath)
+	noErr(err)
+	assert.Equal(os.FileMode(0600), stat.Mode().Perm())
+
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+	resp, err = unixClient.Get("http://unix/hello")
+	noErr(err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("hello", string(body))
+}
+
+func TestListenOnListener(t *testing.T) {
+	assert := assert.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	noErr(err)
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/hello",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			return f.Respond.Ok().Text("hello")
+		},
+	})
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- server.ListenOn(listener)
+	}()
+	waitUntil(func() bool { return len(server.Addrs()) == 1 })
+
+	body, resp := request("GET", fmt.Sprintf("http://%s/hello", listener.Addr()), nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("hello", string(body))
+
+	server.Close()
+	assert.ErrorIs(<-listenErr, http.ErrServerClosed)
+}
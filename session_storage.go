@@ -0,0 +1,262 @@
+package butler
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// #region Memory Storage
+
+// SessionStorage that keeps the records in memory, expired records are removed in the background.
+type MemorySessionStorage struct {
+	mx      sync.RWMutex
+	records map[string]SessionRecord
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// Creates a memory storage, expired records are removed every cleanupInterval
+func NewMemorySessionStorage(cleanupInterval time.Duration) *MemorySessionStorage {
+	s := &MemorySessionStorage{
+		records: map[string]SessionRecord{},
+		stop:    make(chan struct{}),
+	}
+	go runSessionCleanup(cleanupInterval, s.stop, s.RemoveExpired)
+	return s
+}
+
+func (s *MemorySessionStorage) Get(ctx context.Context, id string) (*SessionRecord, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	record, ok := s.records[id]
+	if !ok || record.IsExpired() {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (s *MemorySessionStorage) Set(ctx context.Context, id string, record SessionRecord) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.records[id] = record
+	return nil
+}
+
+func (s *MemorySessionStorage) Delete(ctx context.Context, id string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+func (s *MemorySessionStorage) DeleteUserSessions(ctx context.Context, userID string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for id, record := range s.records {
+		if record.UserID == userID {
+			delete(s.records, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemorySessionStorage) RemoveExpired() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for id, record := range s.records {
+		if record.IsExpired() {
+			delete(s.records, id)
+		}
+	}
+}
+
+// Stops the background cleanup
+func (s *MemorySessionStorage) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+// #endregion Memory Storage
+
+// #region File Storage
+
+// SessionStorage that keeps each record in a separate file, expired records are removed in the background.
+type FileSessionStorage struct {
+	dir  string
+	mx   sync.RWMutex
+	stop chan struct{}
+	once sync.Once
+}
+
+const sessionFileExt = ".session"
+
+// Creates a file storage within the given directory, expired records are removed every cleanupInterval
+func NewFileSessionStorage(dir string, cleanupInterval time.Duration) (*FileSessionStorage, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileSessionStorage{
+		dir:  dir,
+		stop: make(chan struct{}),
+	}
+	go runSessionCleanup(cleanupInterval, s.stop, s.RemoveExpired)
+	return s, nil
+}
+
+func (s *FileSessionStorage) filePath(id string) (string, error) {
+	// ids are generated as base64url, anything else could be used to escape the directory
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", errors.New("invalid session id")
+	}
+	return filepath.Join(s.dir, id+sessionFileExt), nil
+}
+
+func (s *FileSessionStorage) Get(ctx context.Context, id string) (*SessionRecord, error) {
+	fpath, err := s.filePath(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	record, err := readSessionFile(fpath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.IsExpired() {
+		return nil, nil
+	}
+	return record, nil
+}
+
+func (s *FileSessionStorage) Set(ctx context.Context, id string, record SessionRecord) error {
+	fpath, err := s.filePath(id)
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	err = gob.NewEncoder(&data).Encode(record)
+	if err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	// write to a temp file first, so the readers never see a partially written record
+	tmp := fpath + ".tmp"
+	err = os.WriteFile(tmp, data.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, fpath)
+}
+
+func (s *FileSessionStorage) Delete(ctx context.Context, id string) error {
+	fpath, err := s.filePath(id)
+	if err != nil {
+		return nil
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	err = os.Remove(fpath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileSessionStorage) DeleteUserSessions(ctx context.Context, userID string) error {
+	return s.removeWhere(func(record *SessionRecord) bool {
+		return record.UserID == userID
+	})
+}
+
+func (s *FileSessionStorage) RemoveExpired() {
+	s.removeWhere(func(record *SessionRecord) bool {
+		return record.IsExpired()
+	})
+}
+
+// Stops the background cleanup
+func (s *FileSessionStorage) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *FileSessionStorage) removeWhere(predicate func(record *SessionRecord) bool) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionFileExt) {
+			continue
+		}
+
+		fpath := filepath.Join(s.dir, entry.Name())
+		record, err := readSessionFile(fpath)
+		if err != nil || predicate(record) {
+			os.Remove(fpath)
+		}
+	}
+
+	return nil
+}
+
+func readSessionFile(fpath string) (*SessionRecord, error) {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var record SessionRecord
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// #endregion File Storage
+
+func runSessionCleanup(interval time.Duration, stop chan struct{}, cleanup func()) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cleanup()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package butler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// Session value key under which the ID of the user owning the session is stored, see SetSessionUser()
+const SessionUserKey = "_butler_user_id"
+
+// SessionRecord is the persisted form of a session
+type SessionRecord struct {
+	// ID of the user owning the session, used to revoke all the sessions of a user
+	UserID string
+	// gob encoded session values
+	Data      []byte
+	ExpiresAt time.Time
+}
+
+func (r *SessionRecord) IsExpired() bool {
+	return !r.ExpiresAt.IsZero() && time.Now().After(r.ExpiresAt)
+}
+
+// SessionStorage persists the session records of the SessionStore. Implement this interface to keep
+// the sessions in an external storage (e.g. Redis or a database).
+type SessionStorage interface {
+	// Returns the record with the given ID, or nil if it does not exist
+	Get(ctx context.Context, id string) (*SessionRecord, error)
+	Set(ctx context.Context, id string, record SessionRecord) error
+	Delete(ctx context.Context, id string) error
+	// Deletes all the records owned by the given user
+	DeleteUserSessions(ctx context.Context, userID string) error
+}
+
+type SessionStoreOptions struct {
+	// How long the sessions are kept, counted from the last time the session was changed.
+	//
+	// Default: 24 hours
+	TTL time.Duration
+	// Options of the session cookie.
+	//
+	// Default: Path `/`, HttpOnly, SameSite Lax, MaxAge equal to the TTL
+	Cookie *sessions.Options
+}
+
+// SessionStore is a sessions.Store that keeps only the session ID in the cookie, and the session values
+// in the SessionStorage. Use it with server.SetSessionStore().
+type SessionStore struct {
+	storage SessionStorage
+	ttl     time.Duration
+	cookie  sessions.Options
+}
+
+func NewSessionStore(storage SessionStorage, opts ...SessionStoreOptions) *SessionStore {
+	o := firstOr(opts, SessionStoreOptions{})
+
+	store := &SessionStore{
+		storage: storage,
+		ttl:     o.TTL,
+	}
+	if store.ttl == 0 {
+		store.ttl = 24 * time.Hour
+	}
+
+	if o.Cookie != nil {
+		store.cookie = *o.Cookie
+	} else {
+		store.cookie = sessions.Options{
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   int(store.ttl.Seconds()),
+		}
+	}
+
+	return store
+}
+
+// Creates a SessionStore that keeps the sessions in memory, expired sessions are removed in the background.
+func NewMemorySessionStore(opts ...SessionStoreOptions) *SessionStore {
+	return NewSessionStore(NewMemorySessionStorage(time.Minute), opts...)
+}
+
+// Creates a SessionStore that keeps each session in a separate file within the given directory.
+func NewFileSessionStore(dir string, opts ...SessionStoreOptions) (*SessionStore, error) {
+	storage, err := NewFileSessionStorage(dir, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return NewSessionStore(storage, opts...), nil
+}
+
+func (s *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := s.cookie
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return session, nil
+	}
+
+	record, err := s.storage.Get(r.Context(), cookie.Value)
+	if err != nil {
+		return session, err
+	}
+	if record == nil || record.IsExpired() {
+		return session, nil
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(record.Data)).Decode(&session.Values)
+	if err != nil {
+		return session, err
+	}
+
+	session.ID = cookie.Value
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			err := s.storage.Delete(r.Context(), session.ID)
+			if err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		id, err := generateSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+
+	var data bytes.Buffer
+	err := gob.NewEncoder(&data).Encode(session.Values)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := session.Values[SessionUserKey].(string)
+	err = s.storage.Set(r.Context(), session.ID, SessionRecord{
+		UserID:    userID,
+		Data:      data.Bytes(),
+		ExpiresAt: time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), session.ID, session.Options))
+	return nil
+}
+
+// Deletes the stored session and clears its ID, so that a new ID will be assigned when the session is saved.
+// Should be used on login and on privilege changes, to prevent session fixation.
+func (s *SessionStore) Regenerate(ctx context.Context, session *sessions.Session) error {
+	if session.ID != "" {
+		err := s.storage.Delete(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	return nil
+}
+
+// Deletes all the sessions of the given user ("log out everywhere")
+func (s *SessionStore) RevokeUser(ctx context.Context, userID string) error {
+	return s.storage.DeleteUserSessions(ctx, userID)
+}
+
+// Stops the background cleanup of the storage, if it has one
+func (s *SessionStore) Close() error {
+	if closer, ok := s.storage.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Marks the session as owned by the given user, which allows revoking it with SessionStore.RevokeUser()
+func SetSessionUser(session *sessions.Session, userID string) {
+	session.Values[SessionUserKey] = userID
+}
+
+func generateSessionID() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+var errNoButlerSessionStore = errors.New("the server session store is not a butler.SessionStore")
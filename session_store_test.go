@@ -0,0 +1,164 @@
+package butler_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+func addSessionEndpoints(server *f.Server) {
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "POST",
+		Path:   "/login",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			s, err := request.RegenerateSession()
+			noErr(err)
+			f.SetSessionUser(s, "user-1")
+			s.Values["name"] = "John"
+			noErr(request.AddFlash("welcome back"))
+			return f.Respond.Ok()
+		},
+	})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/me",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			s, err := request.Session()
+			noErr(err)
+			name, _ := s.Values["name"].(string)
+			return f.Respond.Ok().Text(name)
+		},
+	})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "GET",
+		Path:   "/flash",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			flashes, err := request.Flashes()
+			noErr(err)
+			if len(flashes) == 0 {
+				return f.Respond.Ok().Text("")
+			}
+			return f.Respond.Ok().Text(flashes[0].(string))
+		},
+	})
+	server.Add(&f.BasicEndpoint[f.NoParams]{
+		Method: "POST",
+		Path:   "/logout-everywhere",
+		Handler: func(request *f.Request, params f.NoParams) *f.Response {
+			noErr(request.RevokeUserSessions("user-1"))
+			return f.Respond.Ok()
+		},
+	})
+}
+
+func TestSessionStores(t *testing.T) {
+	fileStore, err := f.NewFileSessionStore(t.TempDir())
+	noErr(err)
+
+	stores := map[string]*f.SessionStore{
+		"memory": f.NewMemorySessionStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			server := f.CreateServer()
+			server.SetSessionStore(store)
+			addSessionEndpoints(server)
+
+			baseUrl := startServer(server)
+			defer server.Close()
+
+			newClient := func() *http.Client {
+				jar, _ := cookiejar.New(nil)
+				return &http.Client{Jar: jar}
+			}
+			do := func(client *http.Client, method string, path string) (string, *http.Response) {
+				req, err := http.NewRequest(method, baseUrl+path, nil)
+				noErr(err)
+				resp, err := client.Do(req)
+				noErr(err)
+				body, _ := io.ReadAll(resp.Body)
+				return string(body), resp
+			}
+
+			browser := newClient()
+			phone := newClient()
+
+			// sessions that were not changed are not saved
+			_, resp := do(browser, "GET", "/me")
+			assert.Empty(resp.Cookies())
+
+			_, resp = do(browser, "POST", "/login")
+			assert.Len(resp.Cookies(), 1)
+			firstID := resp.Cookies()[0].Value
+
+			body, resp := do(browser, "GET", "/me")
+			assert.Equal("John", body)
+			assert.Empty(resp.Cookies())
+
+			body, _ = do(browser, "GET", "/flash")
+			assert.Equal("welcome back", body)
+			body, _ = do(browser, "GET", "/flash")
+			assert.Equal("", body)
+
+			// logging in again rotates the session id, and the previous one is no longer valid
+			_, resp = do(browser, "POST", "/login")
+			assert.NotEqual(firstID, resp.Cookies()[0].Value)
+			assert.False(sessionExists(store, firstID))
+
+			do(phone, "POST", "/login")
+			body, _ = do(phone, "GET", "/me")
+			assert.Equal("John", body)
+
+			do(browser, "POST", "/logout-everywhere")
+
+			body, _ = do(browser, "GET", "/me")
+			assert.Equal("", body)
+			body, _ = do(phone, "GET", "/me")
+			assert.Equal("", body)
+		})
+	}
+}
+
+// loads the session through a fresh request, as the store does not expose the storage
+func sessionExists(store *f.SessionStore, id string) bool {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: id})
+	s, err := store.New(req, "session")
+	noErr(err)
+	return !s.IsNew
+}
+
+func TestMemorySessionStorageCleanup(t *testing.T) {
+	assert := assert.New(t)
+
+	storage := f.NewMemorySessionStorage(10 * time.Millisecond)
+	defer storage.Close()
+
+	ctx := context.Background()
+	storage.Set(ctx, "expired", f.SessionRecord{ExpiresAt: time.Now().Add(-time.Second)})
+	storage.Set(ctx, "valid", f.SessionRecord{ExpiresAt: time.Now().Add(time.Hour)})
+
+	record, _ := storage.Get(ctx, "expired")
+	assert.Nil(record)
+
+	time.Sleep(50 * time.Millisecond)
+
+	record, _ = storage.Get(ctx, "valid")
+	assert.NotNil(record)
+
+	storage.Set(ctx, "expired", f.SessionRecord{ExpiresAt: time.Now().Add(-time.Second)})
+	time.Sleep(50 * time.Millisecond)
+	storage.RemoveExpired()
+	record, _ = storage.Get(ctx, "expired")
+	assert.Nil(record)
+}
@@ -15,6 +15,10 @@ type EndpointData struct {
 	ParamsT     TypeStructure
 	BodyT       TypeStructure
 	ResponseT   TypeStructure
+	// Media types of the request body accepted by the endpoint
+	AcceptContentTypes []string
+	// Description of the authorization requirements of the endpoint
+	Requires string
 }
 
 func CreateApiDocumentation(path string, endpoints []EndpointData, e *echo.Echo, m ...echo.MiddlewareFunc) {
@@ -11,6 +11,22 @@ type param interface {
 	IsQueryParam() bool
 }
 
+// implemented by the params that are not sent in the query, e.g. headers and cookies
+type locatedParam interface {
+	ParamLocation() string
+	ParamName(fieldName string) string
+}
+
+// implemented by the params made of multiple query params, e.g. pagination or filters
+type compositeParam interface {
+	DocumentParams(name string, tag reflect.StructTag) []TypeStructure
+}
+
+// implemented by the types documented as a single value instead of their structure, e.g. uploaded files
+type documentedKind interface {
+	DocumentedKind() string
+}
+
 var paramInterface = reflect.TypeOf((*param)(nil)).Elem()
 
 type TypeStructure struct {
@@ -18,6 +34,39 @@ type TypeStructure struct {
 	Kind     string // e.x. string, int, bool, struct, map, slice
 	Nullable bool
 	Children []TypeStructure
+	// Validation rules from the `validate` struct tag, e.g. `required,min=3`
+	Rules string
+	// Where the param is sent, one of: `query`, `path`, `header`, `cookie`. Only set for the params.
+	In string
+	// Param marked as required with the `param` struct tag
+	Required bool
+	// Value used when the param is not present in the request, from the `default` struct tag
+	Default string
+	// Param only used by the endpoints returning lists, e.g. pagination, sorting or filtering
+	ListOnly bool
+}
+
+// Returns the params sent in the given location (`query`, `path`, `header` or `cookie`)
+func (t TypeStructure) ParamsIn(location string) []TypeStructure {
+	var params []TypeStructure
+	for _, child := range t.Children {
+		if child.In == location && child.Kind != "" {
+			params = append(params, child)
+		}
+	}
+	return params
+}
+
+// Returns a copy of the structure without the params that are only used by the list endpoints
+func (t TypeStructure) WithoutListParams() TypeStructure {
+	children := make([]TypeStructure, 0, len(t.Children))
+	for _, child := range t.Children {
+		if !child.ListOnly {
+			children = append(children, child)
+		}
+	}
+	t.Children = children
+	return t
 }
 
 func (t TypeStructure) Format() string {
@@ -32,13 +81,19 @@ func (t TypeStructure) Format() string {
 		return "string"
 	case "bool":
 		return "boolean"
+	case "file":
+		return "file"
 	case "struct":
 		s := "{\n"
 		for _, child := range t.Children {
+			comment := ""
+			if child.Rules != "" {
+				comment = " // " + child.Rules
+			}
 			if child.Nullable {
-				s += fmt.Sprintf("  \"%s\"?: %s\n", child.Name, padLines(child.Format(), 1))
+				s += fmt.Sprintf("  \"%s\"?: %s%s\n", child.Name, padLines(child.Format(), 1), comment)
 			} else {
-				s += fmt.Sprintf("  \"%s\": %s\n", child.Name, padLines(child.Format(), 1))
+				s += fmt.Sprintf("  \"%s\": %s%s\n", child.Name, padLines(child.Format(), 1), comment)
 			}
 		}
 		s += "}"
@@ -86,6 +141,13 @@ func generateTypeStructure(t reflect.Type, name string, isParamsObject bool) Typ
 		Nullable: false,
 	}
 
+	if t.Kind() != reflect.Ptr {
+		if documented, ok := reflect.New(t).Interface().(documentedKind); ok {
+			ts.Kind = documented.DocumentedKind()
+			return ts
+		}
+	}
+
 	switch t.Kind() {
 	case reflect.Ptr:
 
@@ -97,6 +159,14 @@ func generateTypeStructure(t reflect.Type, name string, isParamsObject bool) Typ
 				ts.Kind = p.AcceptedKind()
 				if p.IsQueryParam() {
 					ts.Name = strings.ToLower(ts.Name)
+					ts.In = "query"
+					return ts
+				}
+				if located, ok := p.(locatedParam); ok {
+					ts.Name = located.ParamName(ts.Name)
+					ts.In = located.ParamLocation()
+					// path params are always present in the matched route
+					ts.Required = ts.In == "path"
 					return ts
 				}
 			}
@@ -123,8 +193,21 @@ func generateTypeStructure(t reflect.Type, name string, isParamsObject bool) Typ
 			if jsonName != "" {
 				name = jsonName
 			}
+			if isParamsObject && field.Type.Kind() == reflect.Ptr {
+				if composite, ok := reflect.New(field.Type.Elem()).Interface().(compositeParam); ok {
+					ts.Children = append(ts.Children, composite.DocumentParams(paramNameOf(field), field.Tag)...)
+					continue
+				}
+			}
 			if name != "-" {
 				child := generateTypeStructure(field.Type, name, isParamsObject)
+				child.Rules = field.Tag.Get("validate")
+				if fileRules := field.Tag.Get("file"); fileRules != "" {
+					child.Rules = strings.Trim(child.Rules+","+fileRules, ",")
+				}
+				if child.In != "" {
+					applyParamTag(&child, field)
+				}
 				if child.Kind != "" {
 					ts.Children = append(ts.Children, child)
 				}
@@ -159,6 +242,29 @@ func generateTypeStructure(t reflect.Type, name string, isParamsObject bool) Typ
 	return ts
 }
 
+// name of the param from the `param` struct tag, or the lowercased field name
+func paramNameOf(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("param"), ",")
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// reads the `param:"name,required"` and `default:"value"` tags of a param field
+func applyParamTag(ts *TypeStructure, field reflect.StructField) {
+	options := strings.Split(field.Tag.Get("param"), ",")
+	if name := strings.TrimSpace(options[0]); name != "" {
+		ts.Name = name
+	}
+	for _, option := range options[1:] {
+		if strings.TrimSpace(option) == "required" {
+			ts.Required = true
+		}
+	}
+	ts.Default = field.Tag.Get("default")
+}
+
 func isNumber(k string) bool {
 	return k == "int" || k == "int8" || k == "int32" || k == "int64" || k == "uint" || k == "uint8" || k == "uint16" || k == "uint32" || k == "uint64" || k == "float" || k == "float32" || k == "float64"
 }
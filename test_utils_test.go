@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
+
+	f "github.com/ncpa0cpl/butler"
 )
 
 func waitUntil(predicate func() bool) {
@@ -20,6 +24,14 @@ func waitUntil(predicate func() bool) {
 	}
 }
 
+// starts the server on a random port and returns the base url of it
+func startServer(server *f.Server) string {
+	server.Addresses = []string{"127.0.0.1:0"}
+	go server.Listen()
+	waitUntil(func() bool { return len(server.Addrs()) > 0 })
+	return "http://" + server.Addrs()[0].String()
+}
+
 type header struct {
 	name  string
 	value string
@@ -62,3 +74,21 @@ func noErr(err error) {
 		panic(err)
 	}
 }
+
+// UsageMonitor collecting the records, which are added from the server goroutines
+type recordingMonitor struct {
+	mx      sync.Mutex
+	records []f.UsageRecord
+}
+
+func (m *recordingMonitor) Record(entry *f.UsageRecord) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.records = append(m.records, *entry)
+}
+
+func (m *recordingMonitor) Records() []f.UsageRecord {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return slices.Clone(m.records)
+}
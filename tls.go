@@ -0,0 +1,184 @@
+package butler
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+type TLSSettings struct {
+	// Path to the PEM encoded certificate file. Must be used together with KeyFile.
+	//
+	// Certificate and key files are watched for changes, and reloaded without restarting the server
+	// whenever they are modified on the disk.
+	CertFile string
+	// Path to the PEM encoded private key file. Must be used together with CertFile.
+	KeyFile string
+	// Optional. In-memory TLS configuration. If CertFile and KeyFile are also specified, the
+	// certificate loaded from those files will be used for the connections.
+	Config *tls.Config
+	// How often the certificate files should be checked for changes. Set to a negative value to
+	// disable the hot reload.
+	//
+	// Default: 10 seconds
+	ReloadInterval time.Duration
+	// When set to a non zero value, a plain HTTP listener will be started on the given port,
+	// that will redirect all requests to the HTTPS server. The clients are redirected to the port of the first
+	// TCP listener of the server.
+	RedirectFromPort int
+	// By default HTTP/2 is negotiated with the clients that support it, set to true to only
+	// allow HTTP/1.1
+	DisableHTTP2 bool
+}
+
+func (s *TLSSettings) buildConfig(logger echo.Logger) (*tls.Config, error) {
+	var cfg *tls.Config
+	if s.Config != nil {
+		cfg = s.Config.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+
+	if s.CertFile != "" || s.KeyFile != "" {
+		if s.CertFile == "" || s.KeyFile == "" {
+			return nil, errors.New("both CertFile and KeyFile must be specified")
+		}
+
+		reloader := &certReloader{
+			certFile: s.CertFile,
+			keyFile:  s.KeyFile,
+			interval: s.ReloadInterval,
+			logger:   logger,
+		}
+		if reloader.interval == 0 {
+			reloader.interval = 10 * time.Second
+		}
+
+		err := reloader.load()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = nil
+		cfg.GetCertificate = reloader.GetCertificate
+	}
+
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return nil, errors.New("no certificate was provided in the TLS settings")
+	}
+
+	if !s.DisableHTTP2 && !slices.Contains(cfg.NextProtos, "h2") {
+		cfg.NextProtos = append(cfg.NextProtos, "h2")
+	}
+	if !slices.Contains(cfg.NextProtos, "http/1.1") {
+		cfg.NextProtos = append(cfg.NextProtos, "http/1.1")
+	}
+
+	return cfg, nil
+}
+
+func (s *TLSSettings) createRedirectServer(httpsPort int) *http.Server {
+	return &http.Server{
+		Addr: fmt.Sprintf(":%v", s.RedirectFromPort),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if httpsPort != 443 {
+				host = net.JoinHostPort(host, fmt.Sprintf("%v", httpsPort))
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		}),
+	}
+}
+
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   echo.Logger
+
+	mx        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func (r *certReloader) load() error {
+	certStat, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.certMod = certStat.ModTime()
+	r.keyMod = keyStat.ModTime()
+	r.lastCheck = time.Now()
+	return nil
+}
+
+func (r *certReloader) filesChanged() bool {
+	certStat, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyStat, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+	return !certStat.ModTime().Equal(r.certMod) || !keyStat.ModTime().Equal(r.keyMod)
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.interval < 0 {
+		return r.cert, nil
+	}
+
+	r.mx.RLock()
+	shouldCheck := time.Since(r.lastCheck) >= r.interval
+	cert := r.cert
+	r.mx.RUnlock()
+
+	if !shouldCheck {
+		return cert, nil
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if time.Since(r.lastCheck) < r.interval {
+		return r.cert, nil
+	}
+	r.lastCheck = time.Now()
+
+	if r.filesChanged() {
+		err := r.load()
+		if err != nil {
+			r.logger.Error("failed to reload the TLS certificate: ", err)
+		} else {
+			r.logger.Info("TLS certificate reloaded")
+		}
+	}
+
+	return r.cert, nil
+}
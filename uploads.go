@@ -0,0 +1,622 @@
+package butler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gofrs/uuid"
+	echo "github.com/labstack/echo/v4"
+)
+
+// #region Storage
+
+// Storage the uploaded files are streamed to, while the multipart body is being read
+type FileStorage interface {
+	// Stores the content of the uploaded file, reading it until EOF
+	Store(file *UploadedFile, content io.Reader) (StoredFile, error)
+	// True if the stored files should be removed once the response has been sent
+	Temporary() bool
+}
+
+type StoredFile interface {
+	Open() (io.ReadCloser, error)
+	// Removes the file from the storage
+	Remove() error
+}
+
+// Stores the uploaded files in a temporary directory, the files are removed once the response has been sent
+type TempDirStorage struct {
+	// Default: os.TempDir()
+	Dir string
+}
+
+func (s TempDirStorage) Store(file *UploadedFile, content io.Reader) (StoredFile, error) {
+	f, err := os.CreateTemp(s.Dir, "butler-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	return writeDiskFile(f, content)
+}
+
+func (s TempDirStorage) Temporary() bool {
+	return true
+}
+
+// Stores the uploaded files in the given directory, under a randomly generated name with the extension of the
+// original file. The files are kept after the response has been sent, unless the request failed (the response
+// status is 400 or above).
+type LocalDirStorage struct {
+	Dir string
+}
+
+func (s LocalDirStorage) Store(file *UploadedFile, content io.Reader) (StoredFile, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	name := id.String() + strings.ToLower(filepath.Ext(filepath.Base(file.Filename)))
+	f, err := os.OpenFile(filepath.Join(s.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return writeDiskFile(f, content)
+}
+
+func (s LocalDirStorage) Temporary() bool {
+	return false
+}
+
+func writeDiskFile(f *os.File, content io.Reader) (StoredFile, error) {
+	_, err := io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &DiskFile{Path: f.Name()}, nil
+}
+
+type DiskFile struct {
+	Path string
+}
+
+func (f *DiskFile) Open() (io.ReadCloser, error) {
+	return os.Open(f.Path)
+}
+
+func (f *DiskFile) Remove() error {
+	return os.Remove(f.Path)
+}
+
+// Keeps the uploaded files in memory, should only be used with small size limits
+type MemoryStorage struct{}
+
+func (s MemoryStorage) Store(file *UploadedFile, content io.Reader) (StoredFile, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryFile{Data: data}, nil
+}
+
+func (s MemoryStorage) Temporary() bool {
+	return true
+}
+
+type MemoryFile struct {
+	Data []byte
+}
+
+func (f *MemoryFile) Open() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.Data)), nil
+}
+
+func (f *MemoryFile) Remove() error {
+	f.Data = nil
+	return nil
+}
+
+// #endregion Storage
+
+// #region File Fields
+
+type UploadedFile struct {
+	// Name of the file sent by the client, should not be trusted
+	Filename string
+	// MIME type detected from the content of the file
+	ContentType string
+	Size        int64
+	Stored      StoredFile
+}
+
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	return f.Stored.Open()
+}
+
+// Files uploaded under a single multipart field. The form field name is taken from the `form` struct tag, or the
+// field name if the tag is not present.
+//
+// Limits are set with the `file` struct tag, e.g. `file:"required,max_size=2MB,max_count=3,types=image/png|image/jpeg"`,
+// types can use wildcards, e.g. `image/*`. Files breaking the limits result in a 422 response.
+//
+// Default: one file of any size (within the MaxBodySize of the endpoint) and any type
+type FileField struct {
+	files []*UploadedFile
+}
+
+func (f *FileField) DocumentedKind() string {
+	return "file"
+}
+
+// True if at least one file was uploaded
+func (f *FileField) Has() bool {
+	return len(f.files) > 0
+}
+
+// Returns the first of the uploaded files, or nil if none were uploaded
+func (f *FileField) File() *UploadedFile {
+	if len(f.files) == 0 {
+		return nil
+	}
+	return f.files[0]
+}
+
+func (f *FileField) Files() []*UploadedFile {
+	return f.files
+}
+
+type fileFieldRules struct {
+	index     int
+	pointer   bool
+	formName  string
+	required  bool
+	maxSize   int64
+	sizeLabel string
+	maxCount  int
+	types     []string
+}
+
+var fileFieldType = reflect.TypeFor[FileField]()
+var fileFieldsCache sync.Map
+
+// Returns the FileField fields of the body type, panics if any of the `file` tags is invalid
+func fileFieldsOf(t reflect.Type) map[string]*fileFieldRules {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if rules, ok := fileFieldsCache.Load(t); ok {
+		return rules.(map[string]*fileFieldRules)
+	}
+
+	rules := map[string]*fileFieldRules{}
+	if t.Kind() == reflect.Struct {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.Type != fileFieldType && field.Type != reflect.PointerTo(fileFieldType) {
+				continue
+			}
+
+			r := compileFileRules(field)
+			r.index = i
+			r.pointer = field.Type.Kind() == reflect.Pointer
+			rules[r.formName] = r
+		}
+	}
+
+	fileFieldsCache.Store(t, rules)
+	return rules
+}
+
+func compileFileRules(field reflect.StructField) *fileFieldRules {
+	r := &fileFieldRules{formName: field.Name, maxCount: 1}
+	if name, _, _ := strings.Cut(field.Tag.Get("form"), ","); name != "" {
+		r.formName = name
+	}
+
+	invalid := func(format string, args ...any) {
+		panic(fmt.Sprintf("invalid file tag on field %s: %s", field.Name, fmt.Sprintf(format, args...)))
+	}
+
+	for option := range strings.SplitSeq(field.Tag.Get("file"), ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			r.required = true
+		case "max_size":
+			size, ok := parseByteSize(value)
+			if !ok {
+				invalid("%q is not a valid size", value)
+			}
+			r.maxSize = size
+			r.sizeLabel = value
+		case "max_count":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				invalid("%q is not a positive integer", value)
+			}
+			r.maxCount = count
+		case "types":
+			for t := range strings.SplitSeq(value, "|") {
+				if t = strings.TrimSpace(t); t != "" {
+					r.types = append(r.types, strings.ToLower(t))
+				}
+			}
+		default:
+			invalid("unknown option %q", key)
+		}
+	}
+
+	return r
+}
+
+// parses sizes like `512`, `100KB`, `5MB` or `1GB`
+func parseByteSize(value string) (int64, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for suffix, m := range map[string]int64{"KB": Units.KB, "MB": Units.MB, "GB": Units.GB} {
+		if num, ok := strings.CutSuffix(value, suffix); ok {
+			value, multiplier = num, m
+			break
+		}
+	}
+	value = strings.TrimSuffix(value, "B")
+
+	size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || size < 1 {
+		return 0, false
+	}
+	return size * multiplier, true
+}
+
+func (r *fileFieldRules) allowsType(detected *mimetype.MIME) bool {
+	if len(r.types) == 0 {
+		return true
+	}
+	for _, t := range r.types {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(detected.String(), prefix+"/") {
+				return true
+			}
+		} else if detected.Is(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// #endregion File Fields
+
+// #region Multipart Decoder
+
+// Decodes `multipart/form-data` bodies, values are bound to the fields by the `form` struct tag, files are
+// streamed to the TempDirStorage and bound to the FileField fields
+var MultipartDecoder = NewMultipartDecoder(TempDirStorage{})
+
+type MultipartOptions struct {
+	// Largest size of a single non-file value, larger values result in a 422 response
+	//
+	// Default: 1MB
+	MaxValueSize int64
+	// Largest combined size of all the non-file values, larger bodies result in a 413 response. The values are
+	// kept in memory, unlike the files, so this limit applies even if the MaxBodySize of the endpoint is disabled.
+	//
+	// Default: 10MB
+	MaxValuesSize int64
+}
+
+// Creates a decoder of `multipart/form-data` bodies that streams the uploaded files to the given storage
+func NewMultipartDecoder(storage FileStorage, opts ...MultipartOptions) BodyDecoder {
+	o := firstOr(opts, MultipartOptions{})
+	if o.MaxValueSize <= 0 {
+		o.MaxValueSize = Units.MB
+	}
+	if o.MaxValuesSize <= 0 {
+		o.MaxValuesSize = 10 * Units.MB
+	}
+
+	return func(request *Request, target any) error {
+		upload := &multipartUpload{
+			request: request,
+			storage: storage,
+			options: o,
+			rules:   fileFieldsOf(reflect.TypeOf(target)),
+			values:  url.Values{},
+			files:   map[string][]*UploadedFile{},
+			tooMany: map[string]bool{},
+		}
+
+		err := upload.read()
+		if err == nil && len(upload.errors) > 0 {
+			err = upload.errors
+		}
+		if err == nil {
+			err = upload.bind(target)
+		}
+
+		if err != nil {
+			upload.removeFiles()
+		} else if storage.Temporary() {
+			request.AfterResponse(upload.removeFiles)
+		} else {
+			request.AfterResponse(upload.discardIfFailed)
+		}
+		return err
+	}
+}
+
+type multipartUpload struct {
+	request *Request
+	storage FileStorage
+	options MultipartOptions
+	rules   map[string]*fileFieldRules
+	values  url.Values
+	files   map[string][]*UploadedFile
+	tooMany map[string]bool
+	stored  []StoredFile
+	// combined size of the non-file values read so far
+	valuesSize int64
+	// true if the form was parsed before the decoder ran
+	parsedForm bool
+	errors     ValidationErrors
+}
+
+func (u *multipartUpload) read() error {
+	httpRequest := u.request.HttpRequest()
+	if httpRequest.MultipartForm != nil {
+		// the body has already been consumed, e.g. by a middleware calling request.FormValue()
+		if err := u.readParsedForm(httpRequest.MultipartForm); err != nil {
+			return err
+		}
+		u.checkRequired()
+		return nil
+	}
+
+	_, params, err := mime.ParseMediaType(httpRequest.Header.Get(echo.HeaderContentType))
+	if err != nil {
+		return err
+	}
+	if params["boundary"] == "" {
+		return errors.New("multipart boundary is missing")
+	}
+
+	reader := multipart.NewReader(httpRequest.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := part.FormName()
+		rules, isFile := u.rules[name]
+
+		switch {
+		case name == "":
+			continue
+		case !isFile && part.FileName() == "":
+			if err := u.readValue(name, part); err != nil {
+				return err
+			}
+		case isFile && part.FileName() != "":
+			if err := u.storeFile(rules, part.FileName(), part); err != nil {
+				return err
+			}
+		}
+		// files sent for fields that are not in the body type are skipped
+	}
+
+	u.checkRequired()
+	return nil
+}
+
+func (u *multipartUpload) readValue(name string, part io.Reader) error {
+	limit := min(u.options.MaxValueSize, u.options.MaxValuesSize-u.valuesSize)
+	// reading a single byte past the limit is enough to tell that the value is too large
+	value, err := io.ReadAll(io.LimitReader(part, limit+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(value)) > limit {
+		if limit < u.options.MaxValueSize {
+			return &http.MaxBytesError{Limit: u.options.MaxValuesSize}
+		}
+		u.errors = append(u.errors, FieldError{
+			Field:   name,
+			Rule:    "max_size",
+			Message: fmt.Sprintf("value must not be larger than %d bytes", u.options.MaxValueSize),
+		})
+		// the rest of the value is skipped without being kept in memory
+		_, err := io.Copy(io.Discard, part)
+		return err
+	}
+
+	u.valuesSize += int64(len(value))
+	u.values.Add(name, string(value))
+	return nil
+}
+
+// Reads the values and files of a form that was parsed by http.Request.ParseMultipartForm
+func (u *multipartUpload) readParsedForm(form *multipart.Form) error {
+	u.parsedForm = true
+	for name, values := range form.Value {
+		if _, isFile := u.rules[name]; !isFile {
+			u.values[name] = values
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(form.File)) {
+		rules, isFile := u.rules[name]
+		if !isFile {
+			continue
+		}
+		for _, header := range form.File[name] {
+			content, err := header.Open()
+			if err != nil {
+				return err
+			}
+			err = u.storeFile(rules, header.Filename, content)
+			content.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (u *multipartUpload) checkRequired() {
+	for _, name := range slices.Sorted(maps.Keys(u.rules)) {
+		// a file that was sent but rejected is already reported
+		rejected := slices.ContainsFunc(u.errors, func(e FieldError) bool { return e.Field == name })
+		if u.rules[name].required && len(u.files[name]) == 0 && !rejected {
+			u.errors = append(u.errors, FieldError{Field: name, Rule: "required", Message: "is required"})
+		}
+	}
+}
+
+func (u *multipartUpload) storeFile(rules *fileFieldRules, filename string, part io.Reader) error {
+	name := rules.formName
+	if len(u.files[name]) >= rules.maxCount {
+		if !u.tooMany[name] {
+			u.tooMany[name] = true
+			u.errors = append(u.errors, FieldError{
+				Field:   name,
+				Rule:    "max_count",
+				Message: fmt.Sprintf("must contain at most %d files", rules.maxCount),
+			})
+		}
+		return nil
+	}
+
+	head := make([]byte, 3072)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	head = head[:n]
+
+	detected := mimetype.Detect(head)
+	if !rules.allowsType(detected) {
+		u.errors = append(u.errors, FieldError{
+			Field:   name,
+			Rule:    "types",
+			Message: fmt.Sprintf("file type %s is not allowed, expected one of: %s", detected.String(), strings.Join(rules.types, ", ")),
+		})
+		return nil
+	}
+
+	file := &UploadedFile{Filename: filename, ContentType: detected.String()}
+	var content io.Reader = io.MultiReader(bytes.NewReader(head), part)
+	if rules.maxSize > 0 {
+		// reading a single byte past the limit is enough to tell that the file is too large
+		content = io.LimitReader(content, rules.maxSize+1)
+	}
+	counter := &countingReader{reader: content}
+
+	stored, err := u.storage.Store(file, counter)
+	if err != nil {
+		return err
+	}
+	u.stored = append(u.stored, stored)
+
+	if rules.maxSize > 0 && counter.count > rules.maxSize {
+		u.errors = append(u.errors, FieldError{
+			Field:   name,
+			Rule:    "max_size",
+			Message: fmt.Sprintf("file must not be larger than %s", rules.sizeLabel),
+		})
+		return nil
+	}
+
+	file.Size = counter.count
+	file.Stored = stored
+	u.files[name] = append(u.files[name], file)
+	return nil
+}
+
+// Assigns the files to the FileField fields and binds the values with the echo form binder
+func (u *multipartUpload) bind(target any) error {
+	body := reflect.ValueOf(target).Elem()
+	for name, rules := range u.rules {
+		field := body.Field(rules.index)
+		if rules.pointer {
+			field.Set(reflect.New(fileFieldType))
+			field = field.Elem()
+		}
+		field.Addr().Interface().(*FileField).files = u.files[name]
+	}
+
+	if u.parsedForm {
+		return (&echo.DefaultBinder{}).BindBody(u.request.EchoContext(), target)
+	}
+
+	// the body has already been read, the values are provided to the echo binder as if it parsed the form itself
+	httpRequest := u.request.HttpRequest()
+	httpRequest.MultipartForm = &multipart.Form{Value: u.values, File: map[string][]*multipart.FileHeader{}}
+	httpRequest.PostForm = u.values
+	httpRequest.Form = url.Values{}
+	for key, values := range u.values {
+		httpRequest.Form[key] = values
+	}
+	for key, values := range httpRequest.URL.Query() {
+		httpRequest.Form[key] = append(httpRequest.Form[key], values...)
+	}
+
+	return (&echo.DefaultBinder{}).BindBody(u.request.EchoContext(), target)
+}
+
+func (u *multipartUpload) removeFiles() {
+	for _, stored := range u.stored {
+		if err := stored.Remove(); err != nil {
+			u.request.Logger.Error("failed to remove an uploaded file: ", err)
+		}
+	}
+	u.stored = nil
+}
+
+// Removes the files kept by a non-temporary storage if the request failed after the body was decoded, e.g. the
+// body validation or the handler returned an error response
+func (u *multipartUpload) discardIfFailed() {
+	if u.request.EchoContext().Response().Status >= 400 {
+		u.removeFiles()
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// #endregion Multipart Decoder
@@ -0,0 +1,346 @@
+package butler_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00")
+
+type uploadPart struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+func multipartBody(parts ...uploadPart) ([]byte, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		if part.filename == "" {
+			noErr(writer.WriteField(part.field, string(part.content)))
+			continue
+		}
+		w, err := writer.CreateFormFile(part.field, part.filename)
+		noErr(err)
+		_, err = w.Write(part.content)
+		noErr(err)
+	}
+	noErr(writer.Close())
+
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+func postMultipart(url string, parts ...uploadPart) (string, int) {
+	body, contentType := multipartBody(parts...)
+	return postBody(url, contentType, body)
+}
+
+type avatarUpload struct {
+	Title       string       `form:"title"`
+	Avatar      f.FileField  `form:"avatar" file:"required,max_size=1KB,types=image/png|image/jpeg"`
+	Attachments *f.FileField `form:"attachments" file:"max_count=2"`
+}
+
+func TestFileUploads(t *testing.T) {
+	assert := assert.New(t)
+
+	var storedPath string
+
+	server := f.CreateServer()
+	server.Add(&f.Endpoint[f.NoParams, avatarUpload]{
+		Method: "POST",
+		Path:   "/avatars",
+		Handler: func(request *f.Request, params f.NoParams, body *avatarUpload) *f.Response {
+			avatar := body.Avatar.File()
+			storedPath = avatar.Stored.(*f.DiskFile).Path
+
+			reader, err := avatar.Open()
+			noErr(err)
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			noErr(err)
+
+			return f.Respond.Ok().JSON(map[string]any{
+				"title":       body.Title,
+				"filename":    avatar.Filename,
+				"contentType": avatar.ContentType,
+				"size":        avatar.Size,
+				"matches":     bytes.Equal(content, pngHeader),
+				"attachments": len(body.Attachments.Files()),
+			})
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, status := postMultipart(
+		baseUrl+"/avatars",
+		uploadPart{field: "title", content: []byte("Me")},
+		uploadPart{field: "avatar", filename: "me.png", content: pngHeader},
+		uploadPart{field: "attachments", filename: "a.txt", content: []byte("a")},
+	)
+	assert.Equal(200, status)
+	assert.JSONEq(`{
+		"title": "Me",
+		"filename": "me.png",
+		"contentType": "image/png",
+		"size": 29,
+		"matches": true,
+		"attachments": 1
+	}`, body)
+
+	// temp files are removed once the response has been sent
+	waitUntil(func() bool {
+		_, err := os.Stat(storedPath)
+		return os.IsNotExist(err)
+	})
+
+	body, status = postMultipart(
+		baseUrl+"/avatars",
+		uploadPart{field: "avatar", filename: "me.png", content: []byte("plain text")},
+		uploadPart{field: "attachments", filename: "a.txt", content: []byte("a")},
+		uploadPart{field: "attachments", filename: "b.txt", content: []byte("b")},
+		uploadPart{field: "attachments", filename: "c.txt", content: []byte("c")},
+	)
+	assert.Equal(422, status)
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "one or more fields are invalid",
+		"errors": [
+			{"field": "avatar", "rule": "types", "message": "file type text/plain; charset=utf-8 is not allowed, expected one of: image/png, image/jpeg"},
+			{"field": "attachments", "rule": "max_count", "message": "must contain at most 2 files"}
+		]
+	}`, body)
+
+	large := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 2048)...)
+	body, status = postMultipart(baseUrl+"/avatars", uploadPart{field: "avatar", filename: "me.png", content: large})
+	assert.Equal(422, status)
+	assert.JSONEq(`{
+		"type": "about:blank",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "one or more fields are invalid",
+		"errors": [
+			{"field": "avatar", "rule": "max_size", "message": "file must not be larger than 1KB"}
+		]
+	}`, body)
+
+	body, status = postMultipart(baseUrl+"/avatars", uploadPart{field: "title", content: []byte("Me")})
+	assert.Equal(422, status)
+	assert.Contains(body, `{"field":"avatar","rule":"required","message":"is required"}`)
+}
+
+func TestFileUploadStorages(t *testing.T) {
+	assert := assert.New(t)
+
+	type document struct {
+		Name string      `form:"name" validate:"required"`
+		File f.FileField `form:"file" file:"required,max_size=1KB"`
+	}
+
+	dir := t.TempDir()
+
+	server := f.CreateServer()
+	server.Add(&f.Endpoint[f.NoParams, document]{
+		Method: "POST",
+		Path:   "/documents",
+		BodyDecoders: map[string]f.BodyDecoder{
+			f.MIMEMultipartForm: f.NewMultipartDecoder(f.LocalDirStorage{Dir: dir}),
+		},
+		Handler: func(request *f.Request, params f.NoParams, body *document) *f.Response {
+			if body.Name == "fail" {
+				return f.Respond.Problem(500)
+			}
+			return f.Respond.Ok().Text(filepath.Base(body.File.File().Stored.(*f.DiskFile).Path))
+		},
+	})
+	server.Add(&f.Endpoint[f.NoParams, document]{
+		Method: "POST",
+		Path:   "/memory",
+		BodyDecoders: map[string]f.BodyDecoder{
+			f.MIMEMultipartForm: f.NewMultipartDecoder(f.MemoryStorage{}),
+		},
+		Handler: func(request *f.Request, params f.NoParams, body *document) *f.Response {
+			return f.Respond.Ok().Text(string(body.File.File().Stored.(*f.MemoryFile).Data))
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, status := postMultipart(
+		baseUrl+"/documents",
+		uploadPart{field: "name", content: []byte("notes")},
+		uploadPart{field: "file", filename: "notes.TXT", content: []byte("hello")},
+	)
+	assert.Equal(200, status)
+	assert.True(strings.HasSuffix(body, ".txt"))
+
+	content, err := os.ReadFile(filepath.Join(dir, body))
+	noErr(err)
+	assert.Equal("hello", string(content))
+
+	body, status = postMultipart(
+		baseUrl+"/memory",
+		uploadPart{field: "name", content: []byte("notes")},
+		uploadPart{field: "file", filename: "notes.txt", content: []byte("in memory")},
+	)
+	assert.Equal(200, status)
+	assert.Equal("in memory", body)
+
+	// files stored for an invalid request are removed
+	_, status = postMultipart(
+		baseUrl+"/documents",
+		uploadPart{field: "name", content: []byte("notes")},
+		uploadPart{field: "file", filename: "small.txt", content: []byte("x")},
+		uploadPart{field: "file", filename: "large.txt", content: bytes.Repeat([]byte("x"), 2048)},
+	)
+	assert.Equal(422, status)
+
+	// as well as the files of requests failing after the body was decoded
+	_, status = postMultipart(baseUrl+"/documents", uploadPart{field: "file", filename: "invalid.txt", content: []byte("x")})
+	assert.Equal(422, status)
+	_, status = postMultipart(
+		baseUrl+"/documents",
+		uploadPart{field: "name", content: []byte("fail")},
+		uploadPart{field: "file", filename: "failed.txt", content: []byte("x")},
+	)
+	assert.Equal(500, status)
+
+	waitUntil(func() bool {
+		entries, err := os.ReadDir(dir)
+		noErr(err)
+		return len(entries) == 1
+	})
+}
+
+func TestFileUploadsWithCSRF(t *testing.T) {
+	assert := assert.New(t)
+
+	type document struct {
+		Name string      `form:"name"`
+		Doc  f.FileField `form:"doc" file:"required"`
+	}
+
+	server := f.CreateServer()
+	server.Use(f.CSRF())
+	server.Add(&f.Endpoint[f.NoParams, document]{
+		Method: "POST",
+		Path:   "/documents",
+		Handler: func(request *f.Request, params f.NoParams, body *document) *f.Response {
+			reader, err := body.Doc.File().Open()
+			noErr(err)
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			noErr(err)
+			return f.Respond.Ok().Text(body.Name + ": " + string(content))
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	post := func(token string) (string, int) {
+		body, contentType := multipartBody(
+			uploadPart{field: "_csrf", content: []byte(token)},
+			uploadPart{field: "name", content: []byte("notes")},
+			uploadPart{field: "doc", filename: "notes.txt", content: []byte("hello")},
+		)
+		req, err := http.NewRequest("POST", baseUrl+"/documents", bytes.NewReader(body))
+		noErr(err)
+		req.Header.Set("Content-Type", contentType)
+		req.AddCookie(&http.Cookie{Name: "_csrf", Value: "token"})
+
+		resp, err := http.DefaultClient.Do(req)
+		noErr(err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		noErr(err)
+		return string(respBody), resp.StatusCode
+	}
+
+	// the token form field is read by the CSRF middleware before the body is decoded
+	body, status := post("token")
+	assert.Equal(200, status)
+	assert.Equal("notes: hello", body)
+
+	_, status = post("forged")
+	assert.Equal(403, status)
+}
+
+func TestMultipartValueLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	type form struct {
+		First  string `form:"first"`
+		Second string `form:"second"`
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.Endpoint[f.NoParams, form]{
+		Method: "POST",
+		Path:   "/form",
+		BodyDecoders: map[string]f.BodyDecoder{
+			f.MIMEMultipartForm: f.NewMultipartDecoder(f.MemoryStorage{}, f.MultipartOptions{
+				MaxValueSize:  8,
+				MaxValuesSize: 12,
+			}),
+		},
+		Handler: func(request *f.Request, params f.NoParams, body *form) *f.Response {
+			return f.Respond.Ok().Text(body.First + body.Second)
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, status := postMultipart(
+		baseUrl+"/form",
+		uploadPart{field: "first", content: []byte("12345678")},
+		uploadPart{field: "second", content: []byte("1234")},
+	)
+	assert.Equal(200, status)
+	assert.Equal("123456781234", body)
+
+	body, status = postMultipart(baseUrl+"/form", uploadPart{field: "first", content: []byte("123456789")})
+	assert.Equal(422, status)
+	assert.Contains(body, `{"field":"first","rule":"max_size","message":"value must not be larger than 8 bytes"}`)
+
+	_, status = postMultipart(
+		baseUrl+"/form",
+		uploadPart{field: "first", content: []byte("12345678")},
+		uploadPart{field: "second", content: []byte("12345")},
+	)
+	assert.Equal(413, status)
+}
+
+func TestInvalidFileTags(t *testing.T) {
+	assert := assert.New(t)
+
+	type badUpload struct {
+		File f.FileField `file:"max_size=huge"`
+	}
+
+	assert.PanicsWithValue(`invalid file tag on field File: "huge" is not a valid size`, func() {
+		f.CreateServer().Add(&f.Endpoint[f.NoParams, badUpload]{
+			Method: "POST",
+			Path:   "/upload",
+			Handler: func(request *f.Request, params f.NoParams, body *badUpload) *f.Response {
+				return f.Respond.Ok()
+			},
+		})
+	})
+}
@@ -3,12 +3,16 @@ package butler
 import "time"
 
 type UsageRecordStep struct {
-	// one of: "auth", "middleware", "handler", "internal:etag", "internal:encoding"
+	// one of: "auth", "authorization", "queue", "middleware:request", "middleware:response", "middleware:around",
+	// "handler", "internal:etag", "internal:encoding"
 	Step string
-	// only for middleware step, name of the middleware
-	Name  string
-	Start *time.Time
-	End   *time.Time
+	// only for middleware steps, name of the middleware
+	Name string
+	// outcome of the step, for the auth and authorization steps one of: "ok", "unauthorized", "forbidden",
+	// for the queue step one of: "ok", "rejected", "timeout"
+	Result string
+	Start  *time.Time
+	End    *time.Time
 }
 
 type UsageRecord struct {
@@ -26,6 +30,7 @@ type UsageMonitor interface {
 type RecordBuilder interface {
 	StepStart(s, name string)
 	StepEnd(s, name string)
+	StepEndWithResult(s, name, result string)
 	GetRecord() *UsageRecord
 }
 
@@ -48,6 +53,8 @@ func (voidRecord) StepStart(step, name string) {}
 
 func (voidRecord) StepEnd(step, name string) {}
 
+func (voidRecord) StepEndWithResult(step, name, result string) {}
+
 func (voidRecord) GetRecord() *UsageRecord {
 	panic("void recorder does not create usage records")
 }
@@ -88,11 +95,17 @@ func (r *usageMonitorRecord) StepStart(step, name string) {
 }
 
 func (r *usageMonitorRecord) StepEnd(step, name string) {
-	for idx := range r.record.Steps {
+	r.StepEndWithResult(step, name, "")
+}
+
+func (r *usageMonitorRecord) StepEndWithResult(step, name, result string) {
+	// search from the end, same step can be started more than once (e.g. when a middleware retries the handler)
+	for idx := len(r.record.Steps) - 1; idx >= 0; idx-- {
 		s := &r.record.Steps[idx]
-		if s.Step == step && s.Name == name {
+		if s.Step == step && s.Name == name && s.End == nil {
 			now := time.Now()
 			s.End = &now
+			s.Result = result
 			return
 		}
 	}
@@ -113,21 +126,27 @@ func createMonitorRecorder(server *Server) monitorRecorder {
 }
 
 type mstep struct {
-	Auth          string
-	ReqMiddleware string
-	ResMiddleware string
-	Handler       string
-	EtagHandler   string
-	Encoding      string
-	Custom        string
+	Auth             string
+	Authorization    string
+	ReqMiddleware    string
+	ResMiddleware    string
+	AroundMiddleware string
+	Queue            string
+	Handler          string
+	EtagHandler      string
+	Encoding         string
+	Custom           string
 }
 
 var MonitorStep = mstep{
-	Auth:          "auth",
-	ReqMiddleware: "middleware:request",
-	ResMiddleware: "middleware:response",
-	Handler:       "handler",
-	EtagHandler:   "internal:etag",
-	Encoding:      "internal:encoding",
-	Custom:        "custom",
+	Auth:             "auth",
+	Authorization:    "authorization",
+	ReqMiddleware:    "middleware:request",
+	ResMiddleware:    "middleware:response",
+	AroundMiddleware: "middleware:around",
+	Queue:            "queue",
+	Handler:          "handler",
+	EtagHandler:      "internal:etag",
+	Encoding:         "internal:encoding",
+	Custom:           "custom",
 }
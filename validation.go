@@ -0,0 +1,475 @@
+package butler
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+// Validator can be implemented by the body and params types to add rules that cannot be expressed with
+// the `validate` struct tags. It runs after the tag rules of the same struct, return ValidationErrors
+// to report multiple fields at once.
+type Validator interface {
+	Validate() error
+}
+
+// Describes a single field that failed the validation
+type FieldError struct {
+	// Path of the field, e.g. `address.city` or `items[2].name`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fe := range e {
+		if fe.Field == "" {
+			messages = append(messages, fe.Message)
+		} else {
+			messages = append(messages, fe.Field+" "+fe.Message)
+		}
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Creates a 422 problem response listing all the failed fields in the `errors` member
+func (e ValidationErrors) Response() *Response {
+	return Respond.FromProblem(NewProblem(422, "one or more fields are invalid").With("errors", e))
+}
+
+// implemented by the param types, so that the validation rules can be applied to the parsed value
+type validatableParam interface {
+	paramValue() (value any, isSet bool)
+}
+
+var validatorInterface = reflect.TypeFor[Validator]()
+var validatableParamInterface = reflect.TypeFor[validatableParam]()
+
+// #region Compilation
+
+type validationRule struct {
+	name  string
+	check func(v reflect.Value) (message string)
+}
+
+type fieldValidator struct {
+	index    int
+	name     string
+	param    bool
+	required bool
+	rules    []validationRule
+	nested   *typeValidator
+}
+
+// typeValidator holds the validation rules of a type, compiled once per type. A nil validator means
+// there is nothing to validate.
+type typeValidator struct {
+	kind      reflect.Kind
+	elem      *typeValidator
+	fields    []fieldValidator
+	validator bool
+}
+
+var validatorCache sync.Map
+
+// Returns the validator of the given type, panics if any of the `validate` tags is invalid
+func validatorFor(t reflect.Type) *typeValidator {
+	if v, ok := validatorCache.Load(t); ok {
+		return v.(*typeValidator)
+	}
+	v := compileValidator(t, map[reflect.Type]*typeValidator{})
+	validatorCache.Store(t, v)
+	return v
+}
+
+func compileValidator(t reflect.Type, visiting map[reflect.Type]*typeValidator) *typeValidator {
+	if v, ok := visiting[t]; ok {
+		// recursive type, the validator is filled once the compilation of the outer type finishes
+		return v
+	}
+
+	tv := &typeValidator{kind: t.Kind()}
+	visiting[t] = tv
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		tv.elem = compileValidator(t.Elem(), visiting)
+		if tv.elem == nil {
+			return nil
+		}
+		return tv
+
+	case reflect.Struct:
+		tv.validator = implementsValidator(t)
+
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			fv := fieldValidator{index: i}
+			valueKind := indirectType(field.Type).Kind()
+
+			if field.Type.Implements(validatableParamInterface) {
+				fv.param = true
+				fv.name = paramNameOf(field)
+				value, _ := reflect.New(field.Type.Elem()).Interface().(validatableParam).paramValue()
+				// params of an interface type hold a nil interface, so only their dynamic value has a kind
+				valueKind = reflect.Interface
+				if valueType := reflect.TypeOf(value); valueType != nil {
+					valueKind = valueType.Kind()
+				}
+			} else {
+				fv.name = jsonFieldName(field)
+				if fv.name == "-" {
+					continue
+				}
+				fv.nested = compileValidator(field.Type, visiting)
+			}
+
+			fv.required, fv.rules = compileRules(field.Name, field.Tag.Get("validate"), valueKind)
+
+			if fv.required || len(fv.rules) > 0 || fv.nested != nil {
+				tv.fields = append(tv.fields, fv)
+			}
+		}
+
+		if len(tv.fields) == 0 && !tv.validator {
+			return nil
+		}
+		return tv
+	}
+
+	tv.validator = implementsValidator(t)
+	if !tv.validator {
+		return nil
+	}
+	return tv
+}
+
+func implementsValidator(t reflect.Type) bool {
+	return t.Implements(validatorInterface) || reflect.PointerTo(t).Implements(validatorInterface)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func compileRules(fieldName string, tag string, kind reflect.Kind) (required bool, rules []validationRule) {
+	if tag == "" {
+		return false, nil
+	}
+
+	invalid := func(reason string) {
+		panic(fmt.Sprintf("invalid validate tag on field %s: %s", fieldName, reason))
+	}
+
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				invalid(fmt.Sprintf("`%s` requires a numeric argument", name))
+			}
+			check := sizeRule(name, arg, limit, kind)
+			if check == nil {
+				invalid(fmt.Sprintf("`%s` cannot be used on a %s", name, kind))
+			}
+			rules = append(rules, validationRule{name, check})
+		case "email", "url", "uuid":
+			if kind != reflect.String {
+				invalid(fmt.Sprintf("`%s` can only be used on strings", name))
+			}
+			rules = append(rules, validationRule{name, formatRule(name)})
+		case "oneof":
+			allowed := strings.Fields(arg)
+			if len(allowed) == 0 {
+				invalid("`oneof` requires at least one value")
+			}
+			message := "must be one of: " + strings.Join(allowed, ", ")
+			rules = append(rules, validationRule{name, func(v reflect.Value) string {
+				if slices.Contains(allowed, fmt.Sprint(v.Interface())) {
+					return ""
+				}
+				return message
+			}})
+		case "":
+		default:
+			invalid(fmt.Sprintf("unknown rule `%s`", name))
+		}
+	}
+
+	return required, rules
+}
+
+func sizeRule(name string, arg string, limit float64, kind reflect.Kind) func(v reflect.Value) string {
+	var size func(v reflect.Value) float64
+	var unit string
+
+	switch kind {
+	case reflect.String:
+		size = func(v reflect.Value) float64 { return float64(utf8.RuneCountInString(v.String())) }
+		unit = " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size = func(v reflect.Value) float64 { return float64(v.Len()) }
+		unit = " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = func(v reflect.Value) float64 { return float64(v.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = func(v reflect.Value) float64 { return float64(v.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		size = func(v reflect.Value) float64 { return v.Float() }
+	default:
+		return nil
+	}
+
+	if unit == "" && name == "len" {
+		return nil
+	}
+
+	verb := "must be"
+	if unit == " items" {
+		verb = "must contain"
+	}
+
+	switch name {
+	case "min":
+		message := fmt.Sprintf("%s at least %s%s", verb, arg, unit)
+		return func(v reflect.Value) string {
+			if size(v) < limit {
+				return message
+			}
+			return ""
+		}
+	case "max":
+		message := fmt.Sprintf("%s at most %s%s", verb, arg, unit)
+		return func(v reflect.Value) string {
+			if size(v) > limit {
+				return message
+			}
+			return ""
+		}
+	default:
+		message := fmt.Sprintf("%s exactly %s%s", verb, arg, unit)
+		return func(v reflect.Value) string {
+			if size(v) != limit {
+				return message
+			}
+			return ""
+		}
+	}
+}
+
+func formatRule(name string) func(v reflect.Value) string {
+	switch name {
+	case "email":
+		return func(v reflect.Value) string {
+			addr, err := mail.ParseAddress(v.String())
+			if err != nil || addr.Address != v.String() {
+				return "must be a valid email address"
+			}
+			return ""
+		}
+	case "url":
+		return func(v reflect.Value) string {
+			u, err := url.ParseRequestURI(v.String())
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return "must be a valid URL"
+			}
+			return ""
+		}
+	default:
+		return func(v reflect.Value) string {
+			if _, err := uuid.FromString(v.String()); err != nil {
+				return "must be a valid UUID"
+			}
+			return ""
+		}
+	}
+}
+
+// #endregion Compilation
+
+// #region Validation
+
+// Validates the value the pointer points to, does nothing if the validator is nil
+func (tv *typeValidator) run(ptr any, errs *ValidationErrors) {
+	if tv == nil {
+		return
+	}
+	tv.validate(reflect.ValueOf(ptr).Elem(), "", errs)
+}
+
+func (tv *typeValidator) validate(v reflect.Value, path string, errs *ValidationErrors) {
+	switch tv.kind {
+	case reflect.Pointer:
+		if !v.IsNil() && tv.elem != nil {
+			tv.elem.validate(v.Elem(), path, errs)
+		}
+		return
+
+	case reflect.Slice, reflect.Array:
+		if tv.elem != nil {
+			for i := range v.Len() {
+				tv.elem.validate(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+		return
+
+	case reflect.Map:
+		if tv.elem != nil {
+			iter := v.MapRange()
+			for iter.Next() {
+				// map values are not addressable, validate a copy so that pointer receivers can be used
+				value := reflect.New(iter.Value().Type()).Elem()
+				value.Set(iter.Value())
+				tv.elem.validate(value, fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), errs)
+			}
+		}
+		return
+
+	case reflect.Struct:
+		for _, fv := range tv.fields {
+			field := v.Field(fv.index)
+			fieldPath := joinFieldPath(path, fv.name)
+
+			if fv.param {
+				fv.checkParam(field, fieldPath, errs)
+			} else {
+				fv.check(field, fieldPath, errs)
+			}
+
+			if fv.nested != nil {
+				fv.nested.validate(field, fieldPath, errs)
+			}
+		}
+	}
+
+	if tv.validator {
+		callValidator(v, path, errs)
+	}
+}
+
+func (fv *fieldValidator) check(v reflect.Value, path string, errs *ValidationErrors) {
+	if !fv.required && len(fv.rules) == 0 {
+		return
+	}
+
+	isPointer := false
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if fv.required {
+				*errs = append(*errs, FieldError{path, "required", "is required"})
+			}
+			return
+		}
+		v = v.Elem()
+		isPointer = true
+	}
+
+	if !isPointer && v.IsZero() {
+		if fv.required {
+			*errs = append(*errs, FieldError{path, "required", "is required"})
+			return
+		}
+		// optional strings are only validated when not empty
+		if v.Kind() == reflect.String {
+			return
+		}
+	}
+
+	fv.applyRules(v, path, errs)
+}
+
+func (fv *fieldValidator) checkParam(v reflect.Value, path string, errs *ValidationErrors) {
+	value, isSet := any(nil), false
+	if !v.IsNil() {
+		value, isSet = v.Interface().(validatableParam).paramValue()
+	}
+
+	if !isSet {
+		if fv.required {
+			*errs = append(*errs, FieldError{path, "required", "is required"})
+		}
+		return
+	}
+
+	fv.applyRules(reflect.ValueOf(value), path, errs)
+}
+
+func (fv *fieldValidator) applyRules(v reflect.Value, path string, errs *ValidationErrors) {
+	for _, rule := range fv.rules {
+		if message := rule.check(v); message != "" {
+			*errs = append(*errs, FieldError{path, rule.name, message})
+		}
+	}
+}
+
+func callValidator(v reflect.Value, path string, errs *ValidationErrors) {
+	var validator Validator
+	if v.CanAddr() {
+		validator, _ = v.Addr().Interface().(Validator)
+	}
+	if validator == nil {
+		validator, _ = v.Interface().(Validator)
+	}
+	if validator == nil {
+		return
+	}
+
+	err := validator.Validate()
+	if err == nil {
+		return
+	}
+
+	var fieldErrs ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		for _, fe := range fieldErrs {
+			fe.Field = joinFieldPath(path, fe.Field)
+			*errs = append(*errs, fe)
+		}
+		return
+	}
+
+	*errs = append(*errs, FieldError{path, "validate", err.Error()})
+}
+
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" {
+		return path
+	}
+	return path + "." + name
+}
+
+// #endregion Validation
@@ -0,0 +1,172 @@
+package butler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	f "github.com/ncpa0cpl/butler"
+	"github.com/stretchr/testify/assert"
+)
+
+type signupAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type signupItem struct {
+	Sku string `json:"sku" validate:"len=6"`
+}
+
+type signupBody struct {
+	Name     string         `json:"name" validate:"required,min=3,max=64"`
+	Email    string         `json:"email" validate:"required,email"`
+	Website  string         `json:"website" validate:"url"`
+	Plan     string         `json:"plan" validate:"oneof=free pro"`
+	Age      *int           `json:"age" validate:"min=18"`
+	Address  *signupAddress `json:"address"`
+	Items    []signupItem   `json:"items" validate:"max=2"`
+	Password string         `json:"password"`
+	Confirm  string         `json:"confirm"`
+}
+
+func (b *signupBody) Validate() error {
+	if b.Password != b.Confirm {
+		return f.ValidationErrors{{Field: "confirm", Rule: "match", Message: "must match the password"}}
+	}
+	return nil
+}
+
+type signupParams struct {
+	Ref   *f.StringQParam `validate:"required,min=3"`
+	Limit *f.NumberQParam `validate:"max=100"`
+}
+
+func TestValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	server := f.CreateServer()
+	server.Add(&f.Endpoint[signupParams, signupBody]{
+		Method: "POST",
+		Path:   "/signup",
+		Handler: func(request *f.Request, params signupParams, body *signupBody) *f.Response {
+			return f.Respond.Created()
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	age := 16
+	body, resp := request("POST", baseUrl+"/signup?limit=500", map[string]any{
+		"name":     "Jo",
+		"email":    "not an email",
+		"website":  "example.com",
+		"plan":     "enterprise",
+		"age":      age,
+		"address":  map[string]any{},
+		"items":    []any{map[string]any{"sku": "ABC"}, map[string]any{"sku": "ABCDEF"}, map[string]any{"sku": "XYZ123"}},
+		"password": "secret",
+		"confirm":  "secret!",
+	})
+	assert.Equal(422, resp.StatusCode)
+
+	var result struct {
+		Message string         `json:"message"`
+		Errors  []f.FieldError `json:"errors"`
+	}
+	noErr(json.Unmarshal(body, &result))
+
+	failed := map[string]string{}
+	for _, fe := range result.Errors {
+		failed[fe.Field] = fe.Rule
+	}
+	assert.Equal(map[string]string{
+		"ref":          "required",
+		"limit":        "max",
+		"name":         "min",
+		"email":        "email",
+		"website":      "url",
+		"plan":         "oneof",
+		"age":          "min",
+		"address.city": "required",
+		"items[0].sku": "len",
+		"items":        "max",
+		"confirm":      "match",
+	}, failed)
+
+	_, resp = request("POST", baseUrl+"/signup?ref=newsletter", map[string]any{
+		"name":     "John",
+		"email":    "john@example.com",
+		"plan":     "pro",
+		"password": "secret",
+		"confirm":  "secret",
+	})
+	assert.Equal(201, resp.StatusCode)
+}
+
+func TestValidationInvalidTag(t *testing.T) {
+	type body struct {
+		Active bool `validate:"email"`
+	}
+
+	server := f.CreateServer()
+	assert.PanicsWithValue(t, "invalid validate tag on field Active: `email` can only be used on strings", func() {
+		server.Add(&f.Endpoint[f.NoParams, body]{
+			Method: "POST",
+			Path:   "/",
+			Handler: func(request *f.Request, params f.NoParams, body *body) *f.Response {
+				return f.Respond.Ok()
+			},
+		})
+	})
+}
+
+type shape interface {
+	Sides() int
+}
+
+type polygon int
+
+func (p polygon) Sides() int {
+	return int(p)
+}
+
+func parseShape(value string) (shape, error) {
+	switch value {
+	case "triangle":
+		return polygon(3), nil
+	case "square":
+		return polygon(4), nil
+	}
+	return nil, errors.New("unknown shape")
+}
+
+func TestValidationOfInterfaceParams(t *testing.T) {
+	assert := assert.New(t)
+
+	f.RegisterParamParser(parseShape)
+
+	type shapeParams struct {
+		Shape *f.QParam[shape] `validate:"required"`
+	}
+
+	server := f.CreateServer()
+	server.Add(&f.BasicEndpoint[shapeParams]{
+		Method: "GET",
+		Path:   "/sides",
+		Handler: func(request *f.Request, params shapeParams) *f.Response {
+			return f.Respond.Ok().JSON(params.Shape.Get().Sides())
+		},
+	})
+
+	baseUrl := startServer(server)
+	defer server.Close()
+
+	body, resp := request("GET", baseUrl+"/sides?shape=square", nil)
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("4", string(body))
+
+	body, resp = request("GET", baseUrl+"/sides", nil)
+	assert.Equal(422, resp.StatusCode)
+	assert.Contains(string(body), `{"field":"shape","rule":"required","message":"is required"}`)
+}